@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hostnameLabel 域名中的一段，规则见 RFC 1123
+var hostnameLabel = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,61}[A-Za-z0-9])?$`)
+
+// isHostname 判断是否为合法域名
+func isHostname(host string) bool {
+	host = strings.TrimSuffix(host, ".")
+	if host == "" || len(host) > 253 {
+		return false
+	}
+	labels := strings.Split(host, ".")
+	for _, label := range labels {
+		if !hostnameLabel.MatchString(label) {
+			return false
+		}
+	}
+	// 最后一段全是数字说明是写错的 IPv4，而不是域名
+	_, err := strconv.Atoi(labels[len(labels)-1])
+	return err != nil
+}
+
+// trimBrackets 去掉 IPv6 地址两侧的方括号，frpc 拼接端口时会自行加上
+func trimBrackets(addr string) string {
+	if strings.HasPrefix(addr, "[") && strings.HasSuffix(addr, "]") {
+		return addr[1 : len(addr)-1]
+	}
+	return addr
+}
+
+// validHost 判断地址是否为 IPv4、IPv6 或域名，IPv6 可以带方括号
+func validHost(addr string) bool {
+	host := trimBrackets(addr)
+	if host != addr {
+		ip := net.ParseIP(host)
+		return ip != nil && ip.To4() == nil
+	}
+	return net.ParseIP(host) != nil || isHostname(host)
+}
+
+// hostResolver 域名解析接口，net.DefaultResolver 即满足，也便于替换为本地桩
+type hostResolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// serverResolver 预览服务器地址解析结果时使用的解析器
+var serverResolver hostResolver = net.DefaultResolver
+
+// resolveServerAddr 返回服务器地址对应的 IP，IP 地址原样返回，域名交给 r 解析
+func resolveServerAddr(ctx context.Context, r hostResolver, addr string) ([]string, error) {
+	host := trimBrackets(strings.TrimSpace(addr))
+	if ip := net.ParseIP(host); ip != nil {
+		return []string{ip.String()}, nil
+	}
+	if !isHostname(host) {
+		return nil, fmt.Errorf("无效的服务器地址 %q", addr)
+	}
+	addrs, err := r.LookupHost(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %v", host, err)
+	}
+	return addrs, nil
+}
+
+// maskIP 隐藏服务器地址中间的部分，用于生成配置名称
+func maskIP(addr string) string {
+	host := trimBrackets(addr)
+	ip := net.ParseIP(host)
+	if ip == nil {
+		// 域名不做隐藏
+		return strings.ToLower(host)
+	}
+	if ip.To4() != nil {
+		// IPv4 隐藏第三段，例如：192.168.123.456 -> 192.168.^^^.456
+		parts := strings.Split(ip.String(), ".")
+		parts[2] = "^^^"
+		return strings.Join(parts, ".")
+	}
+	// IPv6 只保留首尾两组，例如：2001:db8::1:2 -> 2001:^^^:2，
+	// 压缩写法的首尾可能为空，例如 ::1 -> ^^^:1
+	groups := strings.Split(ip.String(), ":")
+	masked := []string{"^^^"}
+	if first := groups[0]; first != "" {
+		masked = append([]string{first}, masked...)
+	}
+	if last := groups[len(groups)-1]; last != "" {
+		masked = append(masked, last)
+	}
+	return strings.Join(masked, ":")
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// stubResolver 按表返回解析结果，并记录查询过的域名
+type stubResolver struct {
+	hosts   map[string][]string
+	queried []string
+}
+
+func (r *stubResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	r.queried = append(r.queried, host)
+	if addrs, ok := r.hosts[host]; ok {
+		return addrs, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func TestResolveServerAddr(t *testing.T) {
+	tests := []struct {
+		addr    string
+		want    []string
+		wantErr bool
+		lookup  bool // 是否应交给解析器
+	}{
+		{addr: "192.168.1.10", want: []string{"192.168.1.10"}},
+		{addr: " 10.0.0.1 ", want: []string{"10.0.0.1"}},
+		{addr: "2001:db8::1", want: []string{"2001:db8::1"}},
+		{addr: "[2001:db8::1]", want: []string{"2001:db8::1"}},
+		{addr: "[::1]", want: []string{"::1"}},
+		{addr: "frp.example.com", want: []string{"203.0.113.5", "2001:db8::5"}, lookup: true},
+		{addr: "missing.example.com", wantErr: true, lookup: true},
+		{addr: "bad host", wantErr: true},
+		{addr: "192.168.1", wantErr: true},
+	}
+	for _, tt := range tests {
+		r := &stubResolver{hosts: map[string][]string{"frp.example.com": {"203.0.113.5", "2001:db8::5"}}}
+		got, err := resolveServerAddr(context.Background(), r, tt.addr)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: 错误为 %v，期望出错 %v", tt.addr, err, tt.wantErr)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: 得到 %v，期望 %v", tt.addr, got, tt.want)
+		}
+		if (len(r.queried) > 0) != tt.lookup {
+			t.Errorf("%q: 解析器查询了 %v", tt.addr, r.queried)
+		}
+	}
+}
+
+func TestMaskIP(t *testing.T) {
+	tests := map[string]string{
+		"192.168.123.45":  "192.168.^^^.45",
+		"2001:db8::1:2":   "2001:^^^:2",
+		"[2001:db8::1:2]": "2001:^^^:2",
+		"::1":             "^^^:1",
+		"2001:db8::":      "2001:^^^",
+		"::":              "^^^",
+		"FRP.Example.com": "frp.example.com",
+	}
+	for addr, want := range tests {
+		if got := maskIP(addr); got != want {
+			t.Errorf("maskIP(%q) = %q，期望 %q", addr, got, want)
+		}
+	}
+}
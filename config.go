@@ -0,0 +1,402 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ClientConfig 对应一个 frpc 配置文件
+type ClientConfig struct {
+	ServerAddr string          `toml:"serverAddr"`
+	ServerPort int             `toml:"serverPort,omitzero"`
+	Auth       AuthConfig      `toml:"auth,omitempty"`
+	Transport  ClientTransport `toml:"transport,omitempty"`
+	Visitors   []Visitor       `toml:"visitors,omitempty"`
+	Proxies    []Proxy         `toml:"proxies,omitempty"`
+	Includes   []string        `toml:"includes,omitempty"`
+
+	// Extra 保存表单不认识的顶层配置项，写回时原样保留
+	Extra map[string]any `toml:"-"`
+	// Envs 启动 frpc 时注入的环境变量，单独保存，不写入配置文件
+	Envs map[string]string `toml:"-"`
+	// Options 启动器对该配置的选项，单独保存，不写入配置文件
+	Options profileOptions `toml:"-"`
+}
+
+// AuthConfig 鉴权配置，method 为空时 frpc 按 token 处理
+type AuthConfig struct {
+	Method           string     `toml:"method,omitempty"`
+	AdditionalScopes []string   `toml:"additionalScopes,omitempty"`
+	Token            string     `toml:"token,omitempty"`
+	OIDC             OIDCConfig `toml:"oidc,omitempty"`
+}
+
+// OIDCConfig OIDC 客户端凭据
+type OIDCConfig struct {
+	ClientID                 string            `toml:"clientID,omitempty"`
+	ClientSecret             string            `toml:"clientSecret,omitempty"`
+	Audience                 string            `toml:"audience,omitempty"`
+	Scope                    string            `toml:"scope,omitempty"`
+	TokenEndpointURL         string            `toml:"tokenEndpointURL,omitempty"`
+	AdditionalEndpointParams map[string]string `toml:"additionalEndpointParams,omitempty"`
+}
+
+// ClientTransport 与服务端之间的传输设置，零值表示使用 frpc 默认值
+type ClientTransport struct {
+	Protocol             string          `toml:"protocol,omitempty"`
+	ConnectServerLocalIP string          `toml:"connectServerLocalIP,omitempty"`
+	PoolCount            int             `toml:"poolCount,omitzero"`
+	TCPMux               *bool           `toml:"tcpMux,omitempty"`
+	HeartbeatInterval    int             `toml:"heartbeatInterval,omitzero"`
+	HeartbeatTimeout     int             `toml:"heartbeatTimeout,omitzero"`
+	TLS                  TLSClientConfig `toml:"tls,omitempty"`
+}
+
+// TLSClientConfig 传输层 TLS 设置，frpc 默认启用 TLS
+type TLSClientConfig struct {
+	Enable        *bool  `toml:"enable,omitempty"`
+	CertFile      string `toml:"certFile,omitempty"`
+	KeyFile       string `toml:"keyFile,omitempty"`
+	TrustedCaFile string `toml:"trustedCaFile,omitempty"`
+	ServerName    string `toml:"serverName,omitempty"`
+}
+
+// Visitor 访问者配置
+type Visitor struct {
+	Name           string `toml:"name"`
+	Type           string `toml:"type"`
+	ServerName     string `toml:"serverName,omitempty"`
+	SecretKey      string `toml:"secretKey,omitempty"`
+	BindAddr       string `toml:"bindAddr,omitempty"`
+	BindPort       int    `toml:"bindPort,omitzero"`
+	KeepTunnelOpen bool   `toml:"keepTunnelOpen,omitempty"`
+
+	Extra map[string]any `toml:"-"`
+}
+
+// Proxy 代理配置
+type Proxy struct {
+	Name       string `toml:"name"`
+	Type       string `toml:"type"`
+	LocalIP    string `toml:"localIP,omitempty"`
+	LocalPort  int    `toml:"localPort,omitzero"`
+	RemotePort int    `toml:"remotePort,omitzero"`
+	SecretKey  string `toml:"secretKey,omitempty"`
+
+	Transport ProxyTransport `toml:"transport,omitempty"`
+
+	// http/https/tcpmux 类型的域名路由
+	CustomDomains     []string         `toml:"customDomains,omitempty"`
+	SubDomain         string           `toml:"subdomain,omitempty"`
+	Locations         []string         `toml:"locations,omitempty"`
+	HostHeaderRewrite string           `toml:"hostHeaderRewrite,omitempty"`
+	HTTPUser          string           `toml:"httpUser,omitempty"`
+	HTTPPassword      string           `toml:"httpPassword,omitempty"`
+	RequestHeaders    HeaderOperations `toml:"requestHeaders,omitempty"`
+
+	// tcpmux 类型的端口复用
+	Multiplexer     string `toml:"multiplexer,omitempty"`
+	RouteByHTTPUser string `toml:"routeByHTTPUser,omitempty"`
+
+	LoadBalancer LoadBalancerConfig `toml:"loadBalancer,omitempty"`
+	HealthCheck  HealthCheckConfig  `toml:"healthCheck,omitempty"`
+	Plugin       *ClientPlugin      `toml:"plugin,omitempty"`
+
+	Extra map[string]any `toml:"-"`
+}
+
+// ProxyTransport 代理的加密、压缩和限速设置
+type ProxyTransport struct {
+	UseEncryption      bool   `toml:"useEncryption,omitempty"`
+	UseCompression     bool   `toml:"useCompression,omitempty"`
+	BandwidthLimit     string `toml:"bandwidthLimit,omitempty"`
+	BandwidthLimitMode string `toml:"bandwidthLimitMode,omitempty"`
+}
+
+// LoadBalancerConfig 负载均衡分组，同组代理由 frps 轮流分发流量
+type LoadBalancerConfig struct {
+	Group    string `toml:"group,omitempty"`
+	GroupKey string `toml:"groupKey,omitempty"`
+}
+
+// HealthCheckConfig 代理健康检查，零值表示使用 frpc 默认值
+type HealthCheckConfig struct {
+	Type            string `toml:"type,omitempty"`
+	TimeoutSeconds  int    `toml:"timeoutSeconds,omitzero"`
+	MaxFailed       int    `toml:"maxFailed,omitzero"`
+	IntervalSeconds int    `toml:"intervalSeconds,omitzero"`
+	Path            string `toml:"path,omitempty"`
+}
+
+// ClientPlugin 代理插件，各字段按插件类型取用
+type ClientPlugin struct {
+	Type              string           `toml:"type"`
+	UnixPath          string           `toml:"unixPath,omitempty"`
+	LocalPath         string           `toml:"localPath,omitempty"`
+	StripPrefix       string           `toml:"stripPrefix,omitempty"`
+	LocalAddr         string           `toml:"localAddr,omitempty"`
+	CrtPath           string           `toml:"crtPath,omitempty"`
+	KeyPath           string           `toml:"keyPath,omitempty"`
+	HostHeaderRewrite string           `toml:"hostHeaderRewrite,omitempty"`
+	HTTPUser          string           `toml:"httpUser,omitempty"`
+	HTTPPassword      string           `toml:"httpPassword,omitempty"`
+	Username          string           `toml:"username,omitempty"`
+	Password          string           `toml:"password,omitempty"`
+	RequestHeaders    HeaderOperations `toml:"requestHeaders,omitempty"`
+}
+
+// HeaderOperations 请求头改写
+type HeaderOperations struct {
+	Set map[string]string `toml:"set,omitempty"`
+}
+
+// encodeConfig 将配置序列化为 TOML，所有写配置文件的路径都经过这里
+func encodeConfig(cfg *ClientConfig) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := toml.NewEncoder(&buf)
+	enc.Indent = ""
+	if !cfg.hasExtra() {
+		if err := enc.Encode(cfg); err != nil {
+			return nil, fmt.Errorf("编码配置失败: %v", err)
+		}
+		return buf.Bytes(), nil
+	}
+
+	// 存在未识别的配置项时，先转成 map 再合并，保证它们不会丢失
+	known, err := configToMap(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := enc.Encode(known); err != nil {
+		return nil, fmt.Errorf("编码配置失败: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// configToMap 将配置连同未识别的配置项转换为通用的 map，供其它格式编码使用
+func configToMap(cfg *ClientConfig) (map[string]any, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(cfg); err != nil {
+		return nil, fmt.Errorf("编码配置失败: %v", err)
+	}
+	var known map[string]any
+	if _, err := toml.Decode(buf.String(), &known); err != nil {
+		return nil, fmt.Errorf("编码配置失败: %v", err)
+	}
+	mergeExtra(known, cfg.Extra)
+	mergeListExtra(known, "visitors", len(cfg.Visitors), func(i int) map[string]any { return cfg.Visitors[i].Extra })
+	mergeListExtra(known, "proxies", len(cfg.Proxies), func(i int) map[string]any { return cfg.Proxies[i].Extra })
+	return known, nil
+}
+
+// decodeConfig 解析 TOML 配置，不认识的键保存在各级 Extra 中
+func decodeConfig(data []byte) (*ClientConfig, error) {
+	var raw map[string]any
+	if _, err := toml.Decode(string(data), &raw); err != nil {
+		return nil, fmt.Errorf("解析配置失败: %v", err)
+	}
+	return configFromMap(raw)
+}
+
+// configFromMap 将通用的 map 解析为配置，TOML、YAML、JSON 共用
+func configFromMap(raw map[string]any) (*ClientConfig, error) {
+	cfg := &ClientConfig{}
+	visitors, _ := raw["visitors"].([]map[string]any)
+	proxies, _ := raw["proxies"].([]map[string]any)
+	common := make(map[string]any, len(raw))
+	for k, v := range raw {
+		if (k == "visitors" && visitors != nil) || (k == "proxies" && proxies != nil) {
+			continue
+		}
+		common[k] = v
+	}
+
+	extra, err := decodeWithExtra(common, cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Extra = extra
+	for i, m := range visitors {
+		var v Visitor
+		if v.Extra, err = decodeWithExtra(m, &v); err != nil {
+			return nil, fmt.Errorf("第 %d 个 visitor: %v", i+1, err)
+		}
+		cfg.Visitors = append(cfg.Visitors, v)
+	}
+	for i, m := range proxies {
+		var p Proxy
+		if p.Extra, err = decodeWithExtra(m, &p); err != nil {
+			return nil, fmt.Errorf("第 %d 个 proxy: %v", i+1, err)
+		}
+		cfg.Proxies = append(cfg.Proxies, p)
+	}
+	return cfg, nil
+}
+
+// loadConfig 读取并解析配置文件，格式由扩展名决定
+func loadConfig(path string) (*ClientConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	return decodeConfigAs(configFormat(path), data)
+}
+
+// saveConfig 编码并写入配置文件，格式由扩展名决定
+func saveConfig(path string, cfg *ClientConfig) error {
+	data, err := encodeConfigAs(configFormat(path), cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("保存配置文件失败: %v", err)
+	}
+	return nil
+}
+
+// encodeExtra 将未识别的配置项编码为 TOML 片段
+func encodeExtra(extra map[string]any) (string, error) {
+	if len(extra) == 0 {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	enc := toml.NewEncoder(&buf)
+	enc.Indent = ""
+	if err := enc.Encode(extra); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// decodeExtra 解析用户编辑过的 TOML 片段
+func decodeExtra(text string) (map[string]any, error) {
+	var extra map[string]any
+	if _, err := toml.Decode(text, &extra); err != nil {
+		return nil, err
+	}
+	return extra, nil
+}
+
+// decodeWithExtra 将 raw 解码到 v，返回 v 中没有对应字段的键值
+func decodeWithExtra(raw map[string]any, v any) (map[string]any, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(raw); err != nil {
+		return nil, fmt.Errorf("解析配置失败: %v", err)
+	}
+	md, err := toml.Decode(buf.String(), v)
+	if err != nil {
+		return nil, fmt.Errorf("解析配置失败: %v", err)
+	}
+
+	undecoded := md.Undecoded()
+	unknown := make(map[string]bool, len(undecoded))
+	for _, key := range undecoded {
+		unknown[key.String()] = true
+	}
+	var extra map[string]any
+	for _, key := range undecoded {
+		// 整张未知的表只需要记录一次，已知表下的未知键逐个记录
+		if len(key) > 1 && unknown[key[:len(key)-1].String()] {
+			continue
+		}
+		val, ok := lookupKey(raw, key)
+		if !ok {
+			continue
+		}
+		if extra == nil {
+			extra = map[string]any{}
+		}
+		setKey(extra, key, val)
+	}
+	return extra, nil
+}
+
+// lookupKey 按 TOML 键路径在嵌套 map 中取值
+func lookupKey(m map[string]any, key toml.Key) (any, bool) {
+	var cur any = m
+	for _, k := range key {
+		tbl, ok := cur.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = tbl[k]; !ok {
+			return nil, false
+		}
+	}
+	return cur, cur != nil
+}
+
+// setKey 按 TOML 键路径写入嵌套 map，缺失的中间表会被创建
+func setKey(m map[string]any, key toml.Key, val any) {
+	for _, k := range key[:len(key)-1] {
+		next, ok := m[k].(map[string]any)
+		if !ok {
+			next = map[string]any{}
+			m[k] = next
+		}
+		m = next
+	}
+	m[key[len(key)-1]] = val
+}
+
+// mergeExtra 将 extra 合并进 dst，已有的值优先
+func mergeExtra(dst, extra map[string]any) {
+	for k, v := range extra {
+		cur, exists := dst[k]
+		if !exists {
+			dst[k] = v
+			continue
+		}
+		curTbl, ok1 := cur.(map[string]any)
+		extraTbl, ok2 := v.(map[string]any)
+		if ok1 && ok2 {
+			mergeExtra(curTbl, extraTbl)
+		}
+	}
+}
+
+// mergeListExtra 将数组表中每个元素的 Extra 合并回对应的 map
+func mergeListExtra(dst map[string]any, key string, n int, extraAt func(int) map[string]any) {
+	list, _ := dst[key].([]map[string]any)
+	for i := 0; i < n && i < len(list); i++ {
+		mergeExtra(list[i], extraAt(i))
+	}
+}
+
+// defaultServerPort 未设置 serverPort 时 frpc 连接的端口
+const defaultServerPort = 7000
+
+// serverPort 返回 frpc 实际连接的服务器端口
+func (cfg *ClientConfig) serverPort() int {
+	if cfg.ServerPort == 0 {
+		return defaultServerPort
+	}
+	return cfg.ServerPort
+}
+
+// encryptAllProxies 为所有代理开启加密
+func (cfg *ClientConfig) encryptAllProxies() {
+	for i := range cfg.Proxies {
+		cfg.Proxies[i].Transport.UseEncryption = true
+	}
+}
+
+func (cfg *ClientConfig) hasExtra() bool {
+	if len(cfg.Extra) > 0 {
+		return true
+	}
+	for _, v := range cfg.Visitors {
+		if len(v.Extra) > 0 {
+			return true
+		}
+	}
+	for _, p := range cfg.Proxies {
+		if len(p.Extra) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testConfig 覆盖表单字段、含引号和反斜杠的值，以及保存在 Extra 中的未知配置项
+func testConfig() *ClientConfig {
+	tcpMux := false
+	return &ClientConfig{
+		ServerAddr: "frp.example.com",
+		ServerPort: 7000,
+		Auth:       AuthConfig{Method: "token", Token: `to"ken\with\"quotes`},
+		Transport:  ClientTransport{Protocol: "kcp", TCPMux: &tcpMux},
+		Visitors: []Visitor{{
+			Name: "ssh-visitor", Type: "stcp", ServerName: "ssh", SecretKey: `C:\keys\"a"`, BindPort: 6000,
+			Extra: map[string]any{"bindAddrExtra": "x"},
+		}},
+		Proxies: []Proxy{{
+			Name: "web", Type: "http", LocalPort: 8080, CustomDomains: []string{"a.example.com", `b\"c`},
+			Transport:      ProxyTransport{UseEncryption: true, BandwidthLimit: "1MB"},
+			RequestHeaders: HeaderOperations{Set: map[string]string{"X-Path": `C:\www`}},
+			Extra:          map[string]any{"annotations": map[string]any{"owner": `"ops"`}},
+		}},
+		Extra: map[string]any{
+			"user":      `back\slash`,
+			"log":       map[string]any{"level": "debug", "maxDays": int64(3)},
+			"metadatas": map[string]any{"quote": `say "hi"`},
+		},
+	}
+}
+
+func TestConfigRoundTrip(t *testing.T) {
+	for _, format := range configFormats {
+		t.Run(format, func(t *testing.T) {
+			want := testConfig()
+			data, err := encodeConfigAs(format, want)
+			if err != nil {
+				t.Fatalf("编码失败: %v", err)
+			}
+			got, err := decodeConfigAs(format, data)
+			if err != nil {
+				t.Fatalf("解码失败: %v\n%s", err, data)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("往返后配置不一致\n得到 %#v\n期望 %#v\n%s", got, want, data)
+			}
+		})
+	}
+}
+
+func TestEncodeDecodeConfig(t *testing.T) {
+	want := testConfig()
+	data, err := encodeConfig(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeConfig(data)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, data)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("往返后配置不一致\n得到 %#v\n期望 %#v", got, want)
+	}
+}
+
+func TestSaveLoadKeepsExtra(t *testing.T) {
+	dir := t.TempDir()
+	for _, format := range configFormats {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(dir, "test."+format)
+			want := testConfig()
+			if err := saveConfig(path, want); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// 再保存一次，未知配置项不应丢失
+			if err := saveConfig(path, got); err != nil {
+				t.Fatal(err)
+			}
+			if got, err = loadConfig(path); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Extra, want.Extra) {
+				t.Errorf("顶层未知配置项不一致: %#v", got.Extra)
+			}
+			if !reflect.DeepEqual(got.Proxies[0].Extra, want.Proxies[0].Extra) {
+				t.Errorf("代理未知配置项不一致: %#v", got.Proxies[0].Extra)
+			}
+			if !reflect.DeepEqual(got.Visitors[0].Extra, want.Visitors[0].Extra) {
+				t.Errorf("visitor 未知配置项不一致: %#v", got.Visitors[0].Extra)
+			}
+		})
+	}
+}
+
+// 已知表下有多个未知键时都要保留
+func TestDecodeKeepsUnknownKeysInKnownTables(t *testing.T) {
+	text := `serverAddr = "frp.example.com"
+
+[transport]
+protocol = "tcp"
+dialServerTimeout = 10
+dialServerKeepalive = 7200
+
+[[proxies]]
+name = "ssh"
+type = "tcp"
+localPort = 22
+
+[proxies.transport]
+useEncryption = true
+proxyProtocolVersion = "v2"
+foo = "bar"
+`
+	cfg, err := decodeConfig([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := encodeConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeConfig(data)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, data)
+	}
+	wantExtra := map[string]any{"transport": map[string]any{"dialServerTimeout": int64(10), "dialServerKeepalive": int64(7200)}}
+	if !reflect.DeepEqual(got.Extra, wantExtra) {
+		t.Errorf("顶层未知配置项不一致: %#v\n%s", got.Extra, data)
+	}
+	wantProxy := map[string]any{"transport": map[string]any{"proxyProtocolVersion": "v2", "foo": "bar"}}
+	if !reflect.DeepEqual(got.Proxies[0].Extra, wantProxy) {
+		t.Errorf("代理未知配置项不一致: %#v\n%s", got.Proxies[0].Extra, data)
+	}
+	if got.Transport.Protocol != "tcp" || !got.Proxies[0].Transport.UseEncryption {
+		t.Errorf("已知字段丢失: %#v", got)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"image/color"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// tokenColors 高亮配色，取中间色调以兼容白天和黑暗主题
+var tokenColors = map[tokenKind]color.Color{
+	tokenComment: color.NRGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xff},
+	tokenTable:   color.NRGBA{R: 0x3a, G: 0x7b, B: 0xd5, A: 0xff},
+	tokenKey:     color.NRGBA{R: 0xa6, G: 0x4d, B: 0xc9, A: 0xff},
+	tokenString:  color.NRGBA{R: 0x3d, G: 0x9a, B: 0x50, A: 0xff},
+	tokenValue:   color.NRGBA{R: 0xd0, G: 0x7a, B: 0x1e, A: 0xff},
+}
+
+// errorLineColor 语法错误所在行的背景色
+var errorLineColor = color.NRGBA{R: 0xe0, G: 0x40, B: 0x40, A: 0x50}
+
+// highlightRows 把 TOML 文本转换为带颜色的 TextGrid 行，errLine 为出错的行号（从 1 开始）
+func highlightRows(text string, errLine int) []widget.TextGridRow {
+	lines := strings.Split(text, "\n")
+	rows := make([]widget.TextGridRow, len(lines))
+	for i, line := range lines {
+		runes := []rune(line)
+		cells := make([]widget.TextGridCell, len(runes))
+		for j, r := range runes {
+			cells[j].Rune = r
+		}
+		for _, tok := range highlightTOMLLine(line) {
+			style := &widget.CustomTextGridStyle{FGColor: tokenColors[tok.kind]}
+			for j := tok.start; j < tok.end && j < len(cells); j++ {
+				cells[j].Style = style
+			}
+		}
+		rows[i].Cells = cells
+		if i+1 == errLine {
+			rows[i].Style = &widget.CustomTextGridStyle{BGColor: errorLineColor}
+		}
+	}
+	return rows
+}
+
+// showRawEditor 原始配置编辑器：左侧为纯文本编辑框，TOML 在右侧显示只读的高亮预览，
+// 输入时检查语法和 frpc 不认识的键，有语法错误时必须勾选强制保存才能保存
+func showRawEditor(window fyne.Window, title, format, text string, onSave func(text string) error) {
+	entry := widget.NewMultiLineEntry()
+	entry.TextStyle = fyne.TextStyle{Monospace: true}
+	preview := widget.NewTextGrid()
+	status := widget.NewLabel("")
+	status.Wrapping = fyne.TextWrapWord
+	unknown := widget.NewLabel("")
+	unknown.Wrapping = fyne.TextWrapWord
+	unknown.Importance = widget.WarningImportance
+	force := widget.NewCheck("忽略语法错误强制保存", nil)
+	saveButton := widget.NewButton("保存", nil)
+	saveButton.Importance = widget.HighImportance
+
+	var syntaxErr *syntaxError
+	updateSave := func() {
+		if syntaxErr != nil && !force.Checked {
+			saveButton.Disable()
+		} else {
+			saveButton.Enable()
+		}
+	}
+	check := func(text string) {
+		// 按输入的原文检查，报告的行列与编辑框一致
+		var keys []string
+		syntaxErr, keys = checkSyntax(format, text)
+		if syntaxErr != nil {
+			status.SetText("语法错误 " + syntaxErr.Error())
+			status.Importance = widget.DangerImportance
+			force.Show()
+		} else {
+			status.SetText("语法正确")
+			status.Importance = widget.SuccessImportance
+			force.SetChecked(false)
+			force.Hide()
+		}
+		status.Refresh()
+		if len(keys) > 0 {
+			unknown.SetText("frpc 不认识的配置项: " + strings.Join(keys, ", "))
+			unknown.Show()
+		} else {
+			unknown.Hide()
+		}
+		if format == formatTOML {
+			errLine := 0
+			if syntaxErr != nil {
+				errLine = syntaxErr.line
+			}
+			// TextGrid 会把制表符展开，预览中统一换成空格以便高亮位置对齐
+			preview.Rows = highlightRows(strings.ReplaceAll(strings.ReplaceAll(text, "\r", ""), "\t", "    "), errLine)
+			preview.Refresh()
+		}
+		updateSave()
+	}
+	entry.OnChanged = check
+	force.OnChanged = func(bool) { updateSave() }
+	entry.SetText(text)
+	check(text)
+
+	var body fyne.CanvasObject = entry
+	if format == formatTOML {
+		// 编辑框本身不支持高亮，两侧都加上标题说明分工
+		entryTitle := widget.NewLabel("原文（纯文本编辑，不高亮）")
+		previewTitle := widget.NewLabel("高亮预览（只读，请在左侧编辑）")
+		split := container.NewHSplit(
+			container.NewBorder(entryTitle, nil, nil, nil, entry),
+			container.NewBorder(previewTitle, nil, nil, nil, container.NewScroll(preview)),
+		)
+		split.SetOffset(0.5)
+		body = split
+	}
+	content := container.NewBorder(nil, container.NewVBox(status, unknown, force), nil, nil, body)
+
+	dlg := dialog.NewCustomWithoutButtons(title, content, window)
+	saveButton.OnTapped = func() {
+		if err := onSave(entry.Text); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		dlg.Hide()
+	}
+	dlg.SetButtons([]fyne.CanvasObject{widget.NewButton("取消", dlg.Hide), saveButton})
+	dlg.Resize(fyne.NewSize(900, 600))
+	dlg.Show()
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// envRefPattern frpc 配置模板中的环境变量引用，如 {{ .Envs.FRP_TOKEN }}
+var envRefPattern = regexp.MustCompile(`\{\{-?\s*\.Envs\.([A-Za-z_][A-Za-z0-9_]*)\s*-?\}\}`)
+
+// loadEnv 读取配置的环境变量表，文件不存在时返回空表
+func loadEnv(dir, fileName string) (map[string]string, error) {
+	data, err := readSidecar(sidecarPath(dir, fileName, ".env"), "环境变量")
+	if err != nil || data == nil {
+		return nil, err
+	}
+	envs, err := parseParams(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("解析环境变量失败: %v", err)
+	}
+	return envs, nil
+}
+
+// saveEnv 保存配置的环境变量表，表为空时删除文件
+func saveEnv(dir, fileName string, envs map[string]string) error {
+	var data []byte
+	if len(envs) > 0 {
+		data = []byte(formatParams(envs) + "\n")
+	}
+	return writeSidecar(sidecarPath(dir, fileName, ".env"), "环境变量", data)
+}
+
+// readProfile 读取配置文件及其环境变量表和选项
+func readProfile(dir, envDir, optionsDir, fileName string) (*ClientConfig, error) {
+	cfg, err := loadConfig(filepath.Join(dir, fileName))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Envs, err = loadEnv(envDir, fileName); err != nil {
+		return nil, err
+	}
+	if cfg.Options, err = loadProfileOptions(optionsDir, fileName); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// writeProfile 按选项调整后保存配置文件，环境变量表和选项单独保存
+func writeProfile(dir, envDir, optionsDir, fileName string, cfg *ClientConfig) error {
+	cfg.Options.apply(cfg)
+	if err := saveConfig(filepath.Join(dir, fileName), cfg); err != nil {
+		return err
+	}
+	if err := saveEnv(envDir, fileName, cfg.Envs); err != nil {
+		return err
+	}
+	return saveProfileOptions(optionsDir, fileName, cfg.Options)
+}
+
+// lookupEnv 按 frpc 的方式查找变量，配置自带的变量优先于系统环境变量
+func lookupEnv(envs map[string]string, name string) (string, bool) {
+	if val, ok := envs[name]; ok {
+		return val, true
+	}
+	return os.LookupEnv(name)
+}
+
+// renderEnvs 替换文本中的环境变量引用，返回找不到的变量名
+func renderEnvs(text string, envs map[string]string) (string, []string) {
+	var missing []string
+	seen := map[string]bool{}
+	rendered := envRefPattern.ReplaceAllStringFunc(text, func(ref string) string {
+		name := envRefPattern.FindStringSubmatch(ref)[1]
+		val, ok := lookupEnv(envs, name)
+		if !ok && !seen[name] {
+			seen[name] = true
+			missing = append(missing, name)
+		}
+		return val
+	})
+	sort.Strings(missing)
+	return rendered, missing
+}
+
+// resolveEnvs 返回替换了环境变量引用的配置副本，供校验使用
+func (cfg *ClientConfig) resolveEnvs() (*ClientConfig, []string, error) {
+	data, err := encodeConfig(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !envRefPattern.Match(data) {
+		return cfg, nil, nil
+	}
+	rendered, missing := renderEnvs(string(data), cfg.Envs)
+	resolved, err := decodeConfig([]byte(rendered))
+	if err != nil {
+		return nil, missing, fmt.Errorf("替换环境变量后%v", err)
+	}
+	resolved.Envs = cfg.Envs
+	return resolved, missing, nil
+}
+
+// resolveConfig 读取配置原文，替换环境变量引用后解析，与 frpc 启动时的处理一致
+func resolveConfig(path string, envs map[string]string) (*ClientConfig, []string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	rendered, missing := renderEnvs(string(data), envs)
+	cfg, err := decodeConfigAs(configFormat(path), []byte(rendered))
+	if err != nil {
+		return nil, missing, err
+	}
+	cfg.Envs = envs
+	return cfg, missing, nil
+}
+
+// envList 把环境变量表转换为 cmd.Env 使用的 NAME=VALUE 列表
+func envList(envs map[string]string) []string {
+	list := make([]string, 0, len(envs))
+	for name, val := range envs {
+		list = append(list, name+"="+val)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// missingEnvError 缺少环境变量时的错误
+func missingEnvError(missing []string) error {
+	return fmt.Errorf("缺少环境变量: %s", strings.Join(missing, ", "))
+}
@@ -0,0 +1,1325 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// configForm 新建配置和修改配置共用的表单
+type configForm struct {
+	name        *widget.Entry // 配置名称，只在新建配置时显示
+	serverAddr  *widget.Entry
+	resolveInfo *widget.Label // 服务器地址的解析结果
+	serverPort  *widget.Entry
+	auth        *authForm
+	transport   *transportForm
+	encryptAll  *widget.Check // 保存时为所有代理开启加密
+	includes    *widget.Entry // includes 片段路径
+	envs        *widget.Entry // 环境变量表，单独保存
+	extra       *widget.Entry // 表单不认识的顶层配置项
+	errorLabel  *widget.Label
+	required    map[*widget.Entry]bool // 模板中要求填写的输入框
+	fragment    bool                   // 编辑 includes 片段时只显示代理和 visitor
+
+	// validate 保存前的完整校验，默认合并 includes 片段后校验
+	validate func(cfg *ClientConfig) ValidationErrors
+
+	visitors    []*visitorForm
+	visitorList *fyne.Container
+	proxies     []*proxyForm
+	proxyList   *fyne.Container
+}
+
+// visitorForm 单个 Visitor 的表单项
+type visitorForm struct {
+	box            *fyne.Container
+	name           *widget.Entry
+	typ            *widget.Select
+	serverName     *widget.Entry
+	secretKey      *widget.Entry
+	bindAddr       *widget.Entry
+	bindPort       *widget.Entry
+	keepTunnelOpen *widget.Check
+	extra          *widget.Entry
+}
+
+// proxyForm 单个 Proxy 的表单项
+type proxyForm struct {
+	box        *fyne.Container
+	name       *widget.Entry
+	typ        *widget.Select
+	localAddr  *widget.Entry
+	localPort  *widget.Entry
+	remotePort *widget.Entry
+	portRange  *widget.Check // 端口范围模式，保存时展开为多个代理
+	secretKey  *widget.Entry
+	extra      *widget.Entry
+
+	// tcp/http 类型的负载均衡分组
+	groupBox *fyne.Container
+	group    *widget.Entry
+	groupKey *widget.Entry
+
+	// 加密、压缩和限速
+	useEncryption      *widget.Check
+	useCompression     *widget.Check
+	bandwidthLimit     *widget.Entry
+	bandwidthLimitMode *widget.Select
+
+	// http/https/tcpmux 类型的字段
+	domainBox         *fyne.Container
+	httpBox           *fyne.Container
+	httpAuthBox       *fyne.Container
+	tcpmuxBox         *fyne.Container
+	customDomains     *widget.Entry
+	subdomain         *widget.Entry
+	locations         *widget.Entry
+	hostHeaderRewrite *widget.Entry
+	httpUser          *widget.Entry
+	httpPassword      *widget.Entry
+	requestHeaders    *widget.Entry
+	multiplexer       *widget.Select
+	routeByHTTPUser   *widget.Entry
+
+	healthCheck *healthCheckForm
+	plugin      *pluginForm
+}
+
+func validatePort(port string) bool {
+	p, err := strconv.Atoi(port)
+	return err == nil && p >= 0 && p <= 65535
+}
+
+// showConfigForm 弹出配置表单，点击保存后把表单内容交给 onSave
+func showConfigForm(window fyne.Window, title string, cfg *ClientConfig, onSave func(cfg *ClientConfig) error) {
+	showForm(window, title, newConfigForm(cfg), onSave)
+}
+
+// showNewProfileForm 弹出带配置名称的新建表单，名称留空时按服务器地址生成，required 为模板中必须填写的字段
+func showNewProfileForm(window fyne.Window, name string, cfg *ClientConfig, required []string, onSave func(name string, cfg *ClientConfig) error) {
+	f := newConfigForm(cfg)
+	f.markRequired(required)
+	f.name = widget.NewEntry()
+	f.name.SetPlaceHolder("配置名称，留空则按服务器地址生成")
+	f.name.SetText(name)
+	showForm(window, "新建配置", f, func(cfg *ClientConfig) error {
+		name := sanitizeProfileName(f.name.Text)
+		if name == "" {
+			name = defaultProfileName(cfg)
+		}
+		return onSave(name, cfg)
+	})
+}
+
+// showFragmentForm 弹出 includes 片段的表单，validate 负责与主配置合并后校验
+func showFragmentForm(window fyne.Window, title string, cfg *ClientConfig, validate func(cfg *ClientConfig) ValidationErrors, onSave func(cfg *ClientConfig) error) {
+	f := newConfigForm(cfg)
+	f.fragment = true
+	// 片段跟随主配置的“加密所有代理”，cfg.Options 为主配置的选项
+	f.encryptAll.SetText("加密所有代理（跟随主配置）")
+	f.encryptAll.Disable()
+	f.validate = validate
+	showForm(window, title, f, onSave)
+}
+
+func showForm(window fyne.Window, title string, f *configForm, onSave func(cfg *ClientConfig) error) {
+	// 使用 container.NewVScroll 来实现滚动效果
+	dlg := dialog.NewCustomWithoutButtons(title, container.NewVScroll(f.content()), window)
+	saveButton := widget.NewButton("保存", func() {
+		cfg, err := f.config()
+		if err != nil {
+			f.errorLabel.SetText(err.Error())
+			f.errorLabel.Show()
+			return
+		}
+		if err := onSave(cfg); err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		dlg.Hide()
+	})
+	saveButton.Importance = widget.HighImportance
+	dlg.SetButtons([]fyne.CanvasObject{widget.NewButton("取消", dlg.Hide), saveButton})
+	dlg.Resize(fyne.NewSize(700, 500)) // 调整配置窗口的尺寸
+	dlg.Show()
+}
+
+func newConfigForm(cfg *ClientConfig) *configForm {
+	f := &configForm{
+		serverAddr:  widget.NewEntry(),
+		resolveInfo: widget.NewLabel(""),
+		serverPort:  widget.NewEntry(),
+		auth:        newAuthForm(cfg.Auth),
+		transport:   newTransportForm(cfg.Transport),
+		encryptAll:  widget.NewCheck("加密所有代理", nil),
+		includes:    widget.NewEntry(),
+		envs:        widget.NewMultiLineEntry(),
+		extra:       newExtraEntry(cfg.Extra),
+		errorLabel:  widget.NewLabel(""),
+		required:    map[*widget.Entry]bool{},
+		visitorList: container.NewVBox(),
+		proxyList:   container.NewVBox(),
+	}
+	f.serverAddr.SetPlaceHolder("服务器地址，IP 或域名")
+	f.serverAddr.SetText(cfg.ServerAddr)
+	f.serverAddr.OnChanged = func(text string) {
+		f.resolveInfo.Hide()
+		f.markInvalid(f.serverAddr, validHost(strings.TrimSpace(text)), "无效的服务器地址，应为 IP 或域名")
+	}
+	f.resolveInfo.Wrapping = fyne.TextWrapWord
+	f.resolveInfo.Hide()
+	f.serverPort.SetPlaceHolder(fmt.Sprintf("服务器端口，留空默认为 %d", defaultServerPort))
+	if cfg.ServerPort != 0 {
+		f.serverPort.SetText(strconv.Itoa(cfg.ServerPort))
+	}
+	f.includes.SetPlaceHolder("includes 片段路径，相对配置所在的 src 目录，如 ./confd/*.toml，多个用逗号分隔")
+	f.includes.SetText(strings.Join(cfg.Includes, ", "))
+	f.validate = func(cfg *ClientConfig) ValidationErrors {
+		return validateMerged(srcDir, "", cfg, "", nil)
+	}
+	f.envs.SetPlaceHolder("每行一个 NAME=VALUE，配置中用 {{ .Envs.NAME }} 引用，不会写入配置文件")
+	f.envs.SetText(formatParams(cfg.Envs))
+	f.errorLabel.Hide()
+	f.encryptAll.SetChecked(cfg.Options.EncryptAllProxies)
+	f.encryptAll.OnChanged = func(checked bool) {
+		for _, pf := range f.proxies {
+			pf.applyEncryptAll(checked)
+		}
+	}
+
+	for _, v := range cfg.Visitors {
+		f.addVisitor(v)
+	}
+	for _, p := range cfg.Proxies {
+		f.addProxy(p)
+	}
+	return f
+}
+
+func (f *configForm) content() fyne.CanvasObject {
+	box := container.NewVBox()
+	if f.name != nil {
+		box.Add(widget.NewLabel("配置名称"))
+		box.Add(f.name)
+	}
+	// 片段只包含代理和 visitor，服务器等公共配置写在主配置中
+	if !f.fragment {
+		box.Add(widget.NewLabel("服务器配置项"))
+		box.Add(container.NewBorder(nil, nil, nil, widget.NewButton("解析", f.previewServerAddr), f.serverAddr))
+		box.Add(f.resolveInfo)
+		box.Add(f.serverPort)
+		box.Add(f.auth.box)
+		box.Add(widget.NewAccordion(widget.NewAccordionItem("传输设置", f.transport.box)))
+		box.Add(f.includes)
+	}
+	box.Add(widget.NewLabel("Visitors"))
+	box.Add(f.visitorList)
+	box.Add(widget.NewButton("添加 Visitor", func() { f.addVisitor(Visitor{}) }))
+	box.Add(widget.NewLabel("Proxies"))
+	box.Add(f.encryptAll)
+	box.Add(f.proxyList)
+	box.Add(widget.NewButton("添加 Proxy", func() { f.addProxy(Proxy{}) }))
+	if !f.fragment {
+		box.Add(newEnvAccordion(f.envs))
+	}
+	box.Add(newExtraAccordion(f.extra))
+	box.Add(f.errorLabel)
+	return box
+}
+
+// previewServerAddr 解析服务器地址并在表单中显示结果，不影响保存
+func (f *configForm) previewServerAddr() {
+	addr := f.serverAddr.Text
+	f.resolveInfo.SetText("正在解析...")
+	f.resolveInfo.Show()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		ips, err := resolveServerAddr(ctx, serverResolver, addr)
+		if err != nil {
+			f.resolveInfo.SetText(err.Error())
+			return
+		}
+		f.resolveInfo.SetText("解析结果: " + strings.Join(ips, ", "))
+	}()
+}
+
+// markRequired 标出模板中需要填写的输入框，保存时仍为空会报错
+func (f *configForm) markRequired(fields []string) {
+	entries := f.fieldEntries()
+	for _, field := range fields {
+		if entry, ok := entries[field]; ok {
+			f.required[entry] = true
+			entry.SetPlaceHolder(entry.PlaceHolder + " (必填)")
+		}
+	}
+}
+
+func (f *configForm) markInvalid(entry *widget.Entry, valid bool, errorMsg string) {
+	if valid {
+		entry.Validator = nil
+		entry.SetValidationError(nil)
+		f.errorLabel.Hide()
+	} else {
+		entry.Validator = func(s string) error {
+			return errors.New(errorMsg)
+		}
+		entry.SetValidationError(errors.New(errorMsg))
+		f.errorLabel.SetText(errorMsg)
+		f.errorLabel.Show()
+	}
+}
+
+func (f *configForm) addVisitor(v Visitor) {
+	vf := &visitorForm{
+		name:           widget.NewEntry(),
+		typ:            widget.NewSelect(append([]string(nil), visitorTypes...), nil),
+		serverName:     widget.NewEntry(),
+		secretKey:      widget.NewEntry(),
+		bindAddr:       widget.NewEntry(),
+		bindPort:       widget.NewEntry(),
+		keepTunnelOpen: widget.NewCheck("保持隧道打开", nil),
+		extra:          newExtraEntry(v.Extra),
+	}
+	vf.name.SetPlaceHolder("Visitor 名称")
+	vf.name.SetText(v.Name)
+	vf.typ.PlaceHolder = "类型"
+	setSelectOption(vf.typ, v.Type)
+	vf.serverName.SetPlaceHolder("服务器名称")
+	vf.serverName.SetText(v.ServerName)
+	vf.secretKey.SetPlaceHolder("密钥")
+	vf.secretKey.SetText(v.SecretKey)
+	vf.bindAddr.SetPlaceHolder("绑定地址")
+	vf.bindAddr.SetText(v.BindAddr)
+	vf.bindPort.SetPlaceHolder("绑定端口")
+	if v.BindPort != 0 {
+		vf.bindPort.SetText(strconv.Itoa(v.BindPort))
+	}
+	vf.keepTunnelOpen.SetChecked(v.KeepTunnelOpen)
+	// 只有 xtcp 支持保持隧道打开
+	vf.typ.OnChanged = func(selected string) {
+		setVisible(vf.keepTunnelOpen, selected == "" || selected == "xtcp")
+	}
+	vf.typ.OnChanged(v.Type)
+
+	vf.box = container.NewVBox(
+		widget.NewLabel("Visitor 配置项"),
+		vf.name,
+		vf.typ,
+		vf.serverName,
+		vf.secretKey,
+		vf.bindAddr,
+		vf.bindPort,
+		vf.keepTunnelOpen,
+		newExtraAccordion(vf.extra),
+	)
+	vf.box.Add(widget.NewButton("移除", func() {
+		f.visitorList.Remove(vf.box)
+		for i, item := range f.visitors {
+			if item == vf {
+				f.visitors = append(f.visitors[:i], f.visitors[i+1:]...)
+				break
+			}
+		}
+	}))
+	f.visitors = append(f.visitors, vf)
+	f.visitorList.Add(vf.box)
+}
+
+func (f *configForm) addProxy(p Proxy) {
+	pf := &proxyForm{
+		name:       widget.NewEntry(),
+		typ:        widget.NewSelect(append([]string(nil), proxyTypes...), nil),
+		localAddr:  widget.NewEntry(),
+		localPort:  widget.NewEntry(),
+		remotePort: widget.NewEntry(),
+		portRange:  widget.NewCheck("端口范围模式", nil),
+		secretKey:  widget.NewEntry(),
+		extra:      newExtraEntry(p.Extra),
+
+		group:    widget.NewEntry(),
+		groupKey: widget.NewPasswordEntry(),
+
+		useEncryption:      widget.NewCheck("加密传输", nil),
+		useCompression:     widget.NewCheck("压缩传输", nil),
+		bandwidthLimit:     widget.NewEntry(),
+		bandwidthLimitMode: widget.NewSelect(append([]string(nil), bandwidthLimitModes...), nil),
+
+		customDomains:     widget.NewEntry(),
+		subdomain:         widget.NewEntry(),
+		locations:         widget.NewEntry(),
+		hostHeaderRewrite: widget.NewEntry(),
+		httpUser:          widget.NewEntry(),
+		httpPassword:      widget.NewPasswordEntry(),
+		requestHeaders:    widget.NewMultiLineEntry(),
+		multiplexer:       widget.NewSelect([]string{"httpconnect"}, nil),
+		routeByHTTPUser:   widget.NewEntry(),
+	}
+	pf.name.SetPlaceHolder("Proxy 名称")
+	pf.name.SetText(p.Name)
+	pf.typ.PlaceHolder = "类型"
+
+	pf.localAddr.SetPlaceHolder("本地地址")
+	pf.localAddr.SetText(p.LocalIP)
+	pf.localAddr.OnChanged = func(text string) {
+		f.markInvalid(pf.localAddr, validHost(text), "无效的本地地址")
+	}
+
+	pf.localPort.SetPlaceHolder("本地端口")
+	if p.LocalPort != 0 {
+		pf.localPort.SetText(strconv.Itoa(p.LocalPort))
+	}
+	pf.localPort.OnChanged = func(text string) {
+		pf.checkPort(f, pf.localPort, text)
+	}
+
+	pf.remotePort.SetPlaceHolder("远程端口")
+	if p.RemotePort != 0 {
+		pf.remotePort.SetText(strconv.Itoa(p.RemotePort))
+	}
+	pf.remotePort.OnChanged = func(text string) {
+		pf.checkPort(f, pf.remotePort, text)
+	}
+	pf.portRange.OnChanged = func(checked bool) {
+		if checked {
+			pf.localPort.SetPlaceHolder(fmt.Sprintf("本地端口范围，如 6000-6010,7000，最多 %d 个端口", maxPortRangeSize))
+			pf.remotePort.SetPlaceHolder("远程端口范围，数量与本地端口一致")
+		} else {
+			pf.localPort.SetPlaceHolder("本地端口")
+			pf.remotePort.SetPlaceHolder("远程端口")
+		}
+	}
+
+	pf.secretKey.SetPlaceHolder("密钥")
+	pf.secretKey.SetText(p.SecretKey)
+
+	pf.group.SetPlaceHolder("负载均衡分组名称")
+	pf.group.SetText(p.LoadBalancer.Group)
+	pf.groupKey.SetPlaceHolder("分组密钥，同组代理必须一致")
+	pf.groupKey.SetText(p.LoadBalancer.GroupKey)
+	pf.groupBox = container.NewVBox(pf.group, pf.groupKey)
+
+	pf.useEncryption.SetChecked(p.Transport.UseEncryption)
+	pf.applyEncryptAll(f.encryptAll.Checked)
+	pf.useCompression.SetChecked(p.Transport.UseCompression)
+	pf.bandwidthLimit.SetPlaceHolder("带宽限制，如 1MB、512KB")
+	pf.bandwidthLimit.SetText(p.Transport.BandwidthLimit)
+	pf.bandwidthLimitMode.PlaceHolder = "限速位置 (默认 client)"
+	setSelectOption(pf.bandwidthLimitMode, p.Transport.BandwidthLimitMode)
+
+	pf.customDomains.SetPlaceHolder("自定义域名，多个用逗号分隔")
+	pf.customDomains.SetText(strings.Join(p.CustomDomains, ", "))
+	pf.subdomain.SetPlaceHolder("子域名")
+	pf.subdomain.SetText(p.SubDomain)
+	pf.locations.SetPlaceHolder("路由路径，如 /api, /static")
+	pf.locations.SetText(strings.Join(p.Locations, ", "))
+	pf.hostHeaderRewrite.SetPlaceHolder("改写 Host 请求头")
+	pf.hostHeaderRewrite.SetText(p.HostHeaderRewrite)
+	pf.httpUser.SetPlaceHolder("HTTP 认证用户名")
+	pf.httpUser.SetText(p.HTTPUser)
+	pf.httpPassword.SetPlaceHolder("HTTP 认证密码")
+	pf.httpPassword.SetText(p.HTTPPassword)
+	pf.requestHeaders.SetPlaceHolder("追加请求头，每行一个，如 X-From-Where: frp")
+	pf.requestHeaders.SetText(formatHeaders(p.RequestHeaders.Set))
+	pf.domainBox = container.NewVBox(pf.customDomains, pf.subdomain)
+	pf.multiplexer.PlaceHolder = "复用器"
+	setSelectOption(pf.multiplexer, p.Multiplexer)
+	pf.routeByHTTPUser.SetPlaceHolder("按 HTTP 用户名路由")
+	pf.routeByHTTPUser.SetText(p.RouteByHTTPUser)
+	pf.httpBox = container.NewVBox(
+		pf.locations,
+		pf.hostHeaderRewrite,
+		pf.requestHeaders,
+	)
+	pf.httpAuthBox = container.NewVBox(pf.httpUser, pf.httpPassword)
+	pf.tcpmuxBox = container.NewVBox(pf.multiplexer, pf.routeByHTTPUser)
+
+	pf.healthCheck = newHealthCheckForm(p.HealthCheck)
+	pf.plugin = newPluginForm(p.Plugin, func() {
+		pf.showFieldsFor(pf.typ.Selected)
+	})
+	pf.typ.OnChanged = pf.showFieldsFor
+	pf.showFieldsFor(p.Type)
+	setSelectOption(pf.typ, p.Type)
+
+	pf.box = container.NewVBox(
+		widget.NewLabel("Proxy 配置项"),
+		pf.name,
+		pf.typ,
+		pf.localAddr,
+		pf.portRange,
+		pf.localPort,
+		pf.remotePort,
+		pf.secretKey,
+		pf.groupBox,
+		pf.useEncryption,
+		pf.useCompression,
+		pf.bandwidthLimit,
+		pf.bandwidthLimitMode,
+		pf.domainBox,
+		pf.tcpmuxBox,
+		pf.httpBox,
+		pf.httpAuthBox,
+		pf.healthCheck.box,
+		pf.plugin.box,
+		newExtraAccordion(pf.extra),
+	)
+	pf.box.Add(widget.NewButton("移除", func() {
+		f.proxyList.Remove(pf.box)
+		for i, item := range f.proxies {
+			if item == pf {
+				f.proxies = append(f.proxies[:i], f.proxies[i+1:]...)
+				break
+			}
+		}
+	}))
+	f.proxies = append(f.proxies, pf)
+	f.proxyList.Add(pf.box)
+}
+
+// showFieldsFor 按代理类型显示对应的字段
+func (pf *proxyForm) showFieldsFor(typ string) {
+	// 使用插件时由插件处理流量，不需要本地地址
+	usePlugin := pf.plugin.selected() != ""
+	setVisible(pf.localAddr, !usePlugin)
+	setVisible(pf.localPort, !usePlugin)
+	setVisible(pf.remotePort, typ == "" || typ == "tcp" || typ == "udp")
+	setVisible(pf.portRange, !usePlugin && (typ == "tcp" || typ == "udp"))
+	setVisible(pf.secretKey, typ == "" || typ == "xtcp" || typ == "stcp" || typ == "sudp")
+	setVisible(pf.domainBox, typ == "http" || typ == "https" || typ == "tcpmux")
+	setVisible(pf.tcpmuxBox, typ == "tcpmux")
+	setVisible(pf.httpBox, typ == "http")
+	setVisible(pf.httpAuthBox, typ == "http" || typ == "tcpmux")
+	setVisible(pf.groupBox, typ == "tcp" || typ == "http")
+	// 健康检查针对本地服务，udp 类代理和插件都不支持
+	setVisible(pf.healthCheck.box, !usePlugin && typ != "udp" && typ != "sudp")
+}
+
+// applyEncryptAll 开启“加密所有代理”时强制勾选加密且不允许取消
+func (pf *proxyForm) applyEncryptAll(checked bool) {
+	if checked {
+		pf.useEncryption.SetChecked(true)
+		pf.useEncryption.Disable()
+	} else {
+		pf.useEncryption.Enable()
+	}
+}
+
+// authForm 鉴权设置的表单项
+type authForm struct {
+	box                      *fyne.Container
+	method                   *widget.Select
+	token                    *widget.Entry
+	oidcBox                  *fyne.Container
+	clientID                 *widget.Entry
+	clientSecret             *widget.Entry
+	audience                 *widget.Entry
+	scope                    *widget.Entry
+	tokenEndpointURL         *widget.Entry
+	additionalEndpointParams *widget.Entry
+	additionalScopes         *widget.CheckGroup
+}
+
+func newAuthForm(auth AuthConfig) *authForm {
+	af := &authForm{
+		method:                   widget.NewSelect(append([]string(nil), authMethods...), nil),
+		token:                    widget.NewPasswordEntry(),
+		clientID:                 widget.NewEntry(),
+		clientSecret:             widget.NewPasswordEntry(),
+		audience:                 widget.NewEntry(),
+		scope:                    widget.NewEntry(),
+		tokenEndpointURL:         widget.NewEntry(),
+		additionalEndpointParams: widget.NewMultiLineEntry(),
+		additionalScopes:         widget.NewCheckGroup(append([]string(nil), authScopes...), nil),
+	}
+	af.method.PlaceHolder = "鉴权方式"
+	af.token.SetPlaceHolder("鉴权 Token")
+	af.token.SetText(auth.Token)
+	af.clientID.SetPlaceHolder("OIDC Client ID")
+	af.clientID.SetText(auth.OIDC.ClientID)
+	af.clientSecret.SetPlaceHolder("OIDC Client Secret")
+	af.clientSecret.SetText(auth.OIDC.ClientSecret)
+	af.audience.SetPlaceHolder("Audience")
+	af.audience.SetText(auth.OIDC.Audience)
+	af.scope.SetPlaceHolder("Scope")
+	af.scope.SetText(auth.OIDC.Scope)
+	af.tokenEndpointURL.SetPlaceHolder("Token 端点，如 https://sso.example.com/oauth2/token")
+	af.tokenEndpointURL.SetText(auth.OIDC.TokenEndpointURL)
+	af.additionalEndpointParams.SetPlaceHolder("Token 端点附加参数，每行一个 key=value")
+	af.additionalEndpointParams.SetText(formatParams(auth.OIDC.AdditionalEndpointParams))
+	af.additionalScopes.Horizontal = true
+	af.additionalScopes.SetSelected(auth.AdditionalScopes)
+	af.oidcBox = container.NewVBox(
+		af.clientID,
+		af.clientSecret,
+		af.audience,
+		af.scope,
+		af.tokenEndpointURL,
+		af.additionalEndpointParams,
+	)
+
+	af.method.OnChanged = func(method string) {
+		setVisible(af.token, method != "oidc")
+		setVisible(af.oidcBox, method == "oidc")
+	}
+	// 默认使用 token 鉴权
+	if auth.Method == "" {
+		af.method.SetSelected("token")
+	} else {
+		setSelectOption(af.method, auth.Method)
+	}
+
+	af.box = container.NewVBox(
+		af.method,
+		af.token,
+		af.oidcBox,
+		widget.NewLabel("以下消息同样携带鉴权信息"),
+		af.additionalScopes,
+	)
+	return af
+}
+
+// config 按鉴权方式取出对应字段
+func (af *authForm) config(errs *ValidationErrors) AuthConfig {
+	auth := AuthConfig{
+		Method:           af.method.Selected,
+		AdditionalScopes: af.additionalScopes.Selected,
+	}
+	if auth.Method != "oidc" {
+		auth.Token = af.token.Text
+		return auth
+	}
+	params, err := parseParams(af.additionalEndpointParams.Text)
+	if err != nil {
+		*errs = append(*errs, FieldError{Field: "auth.oidc.additionalEndpointParams", Msg: err.Error()})
+	}
+	auth.OIDC = OIDCConfig{
+		ClientID:                 strings.TrimSpace(af.clientID.Text),
+		ClientSecret:             af.clientSecret.Text,
+		Audience:                 strings.TrimSpace(af.audience.Text),
+		Scope:                    strings.TrimSpace(af.scope.Text),
+		TokenEndpointURL:         strings.TrimSpace(af.tokenEndpointURL.Text),
+		AdditionalEndpointParams: params,
+	}
+	return auth
+}
+
+func (af *authForm) fieldEntries() map[string]*widget.Entry {
+	return map[string]*widget.Entry{
+		"token":                         af.token,
+		"oidc.clientID":                 af.clientID,
+		"oidc.clientSecret":             af.clientSecret,
+		"oidc.tokenEndpointURL":         af.tokenEndpointURL,
+		"oidc.additionalEndpointParams": af.additionalEndpointParams,
+	}
+}
+
+// transportForm 传输设置的表单项
+type transportForm struct {
+	box                  *fyne.Container
+	protocol             *widget.Select
+	connectServerLocalIP *widget.Entry
+	poolCount            *widget.Entry
+	tcpMux               *widget.Check
+	heartbeatInterval    *widget.Entry
+	heartbeatTimeout     *widget.Entry
+	tlsEnable            *widget.Check
+	tlsBox               *fyne.Container
+	certFile             *widget.Entry
+	keyFile              *widget.Entry
+	trustedCaFile        *widget.Entry
+	serverName           *widget.Entry
+}
+
+func newTransportForm(t ClientTransport) *transportForm {
+	tf := &transportForm{
+		protocol:             widget.NewSelect(append([]string(nil), protocols...), nil),
+		connectServerLocalIP: widget.NewEntry(),
+		poolCount:            widget.NewEntry(),
+		tcpMux:               widget.NewCheck("启用 tcpMux 连接复用", nil),
+		heartbeatInterval:    widget.NewEntry(),
+		heartbeatTimeout:     widget.NewEntry(),
+		tlsEnable:            widget.NewCheck("启用 TLS", nil),
+		certFile:             widget.NewEntry(),
+		keyFile:              widget.NewEntry(),
+		trustedCaFile:        widget.NewEntry(),
+		serverName:           widget.NewEntry(),
+	}
+	tf.protocol.PlaceHolder = "传输协议 (默认 tcp)"
+	setSelectOption(tf.protocol, t.Protocol)
+	tf.connectServerLocalIP.SetPlaceHolder("连接服务器时使用的本地 IP")
+	tf.connectServerLocalIP.SetText(t.ConnectServerLocalIP)
+	tf.poolCount.SetPlaceHolder("连接池数量")
+	tf.heartbeatInterval.SetPlaceHolder("心跳间隔 (秒，-1 表示关闭)")
+	tf.heartbeatTimeout.SetPlaceHolder("心跳超时 (秒)")
+	for entry, n := range map[*widget.Entry]int{
+		tf.poolCount:         t.PoolCount,
+		tf.heartbeatInterval: t.HeartbeatInterval,
+		tf.heartbeatTimeout:  t.HeartbeatTimeout,
+	} {
+		if n != 0 {
+			entry.SetText(strconv.Itoa(n))
+		}
+	}
+	// 未设置时与 frpc 的默认值一致，均为开启
+	tf.tcpMux.SetChecked(t.TCPMux == nil || *t.TCPMux)
+	tf.tlsEnable.SetChecked(t.TLS.Enable == nil || *t.TLS.Enable)
+
+	tf.certFile.SetPlaceHolder("客户端证书路径")
+	tf.certFile.SetText(t.TLS.CertFile)
+	tf.keyFile.SetPlaceHolder("客户端私钥路径")
+	tf.keyFile.SetText(t.TLS.KeyFile)
+	tf.trustedCaFile.SetPlaceHolder("CA 证书路径")
+	tf.trustedCaFile.SetText(t.TLS.TrustedCaFile)
+	tf.serverName.SetPlaceHolder("TLS 服务器名称")
+	tf.serverName.SetText(t.TLS.ServerName)
+	tf.tlsBox = container.NewVBox(tf.certFile, tf.keyFile, tf.trustedCaFile, tf.serverName)
+	tf.tlsEnable.OnChanged = func(checked bool) {
+		setVisible(tf.tlsBox, checked)
+	}
+	tf.tlsEnable.OnChanged(tf.tlsEnable.Checked)
+
+	tf.box = container.NewVBox(
+		tf.protocol,
+		tf.connectServerLocalIP,
+		tf.poolCount,
+		tf.tcpMux,
+		tf.heartbeatInterval,
+		tf.heartbeatTimeout,
+		tf.tlsEnable,
+		tf.tlsBox,
+	)
+	return tf
+}
+
+// config 取出传输设置，保持默认值的选项不写入配置文件
+func (tf *transportForm) config(number func(entry *widget.Entry, field string) int) ClientTransport {
+	t := ClientTransport{
+		Protocol:             tf.protocol.Selected,
+		ConnectServerLocalIP: strings.TrimSpace(tf.connectServerLocalIP.Text),
+		PoolCount:            number(tf.poolCount, "transport.poolCount"),
+		HeartbeatInterval:    number(tf.heartbeatInterval, "transport.heartbeatInterval"),
+		HeartbeatTimeout:     number(tf.heartbeatTimeout, "transport.heartbeatTimeout"),
+	}
+	if !tf.tcpMux.Checked {
+		t.TCPMux = new(bool)
+	}
+	if !tf.tlsEnable.Checked {
+		t.TLS.Enable = new(bool)
+		return t
+	}
+	t.TLS.CertFile = strings.TrimSpace(tf.certFile.Text)
+	t.TLS.KeyFile = strings.TrimSpace(tf.keyFile.Text)
+	t.TLS.TrustedCaFile = strings.TrimSpace(tf.trustedCaFile.Text)
+	t.TLS.ServerName = strings.TrimSpace(tf.serverName.Text)
+	return t
+}
+
+func (tf *transportForm) fieldEntries() map[string]*widget.Entry {
+	return map[string]*widget.Entry{
+		"connectServerLocalIP": tf.connectServerLocalIP,
+		"poolCount":            tf.poolCount,
+		"heartbeatInterval":    tf.heartbeatInterval,
+		"heartbeatTimeout":     tf.heartbeatTimeout,
+		"tls.certFile":         tf.certFile,
+		"tls.keyFile":          tf.keyFile,
+		"tls.trustedCaFile":    tf.trustedCaFile,
+		"tls.serverName":       tf.serverName,
+	}
+}
+
+// pluginForm 代理插件的表单项
+type pluginForm struct {
+	box               *fyne.Container
+	typ               *widget.Select
+	unixPath          *widget.Entry
+	localPath         *widget.Entry
+	stripPrefix       *widget.Entry
+	localAddr         *widget.Entry
+	crtPath           *widget.Entry
+	keyPath           *widget.Entry
+	hostHeaderRewrite *widget.Entry
+	httpUser          *widget.Entry
+	httpPassword      *widget.Entry
+	username          *widget.Entry
+	password          *widget.Entry
+	requestHeaders    *widget.Entry
+}
+
+const noPlugin = "不使用插件"
+
+// newPluginForm 创建插件表单，切换插件类型后会调用 onChanged
+func newPluginForm(plugin *ClientPlugin, onChanged func()) *pluginForm {
+	if plugin == nil {
+		plugin = &ClientPlugin{}
+	}
+	pf := &pluginForm{
+		typ:               widget.NewSelect(append([]string{noPlugin}, pluginTypes...), nil),
+		unixPath:          widget.NewEntry(),
+		localPath:         widget.NewEntry(),
+		stripPrefix:       widget.NewEntry(),
+		localAddr:         widget.NewEntry(),
+		crtPath:           widget.NewEntry(),
+		keyPath:           widget.NewEntry(),
+		hostHeaderRewrite: widget.NewEntry(),
+		httpUser:          widget.NewEntry(),
+		httpPassword:      widget.NewPasswordEntry(),
+		username:          widget.NewEntry(),
+		password:          widget.NewPasswordEntry(),
+		requestHeaders:    widget.NewMultiLineEntry(),
+	}
+	pf.typ.PlaceHolder = "插件"
+	pf.unixPath.SetPlaceHolder("Unix 套接字路径")
+	pf.unixPath.SetText(plugin.UnixPath)
+	pf.localPath.SetPlaceHolder("本地目录")
+	pf.localPath.SetText(plugin.LocalPath)
+	pf.stripPrefix.SetPlaceHolder("去除的 URL 前缀")
+	pf.stripPrefix.SetText(plugin.StripPrefix)
+	pf.localAddr.SetPlaceHolder("本地服务地址，如 127.0.0.1:80")
+	pf.localAddr.SetText(plugin.LocalAddr)
+	pf.crtPath.SetPlaceHolder("证书路径")
+	pf.crtPath.SetText(plugin.CrtPath)
+	pf.keyPath.SetPlaceHolder("私钥路径")
+	pf.keyPath.SetText(plugin.KeyPath)
+	pf.hostHeaderRewrite.SetPlaceHolder("改写 Host 请求头")
+	pf.hostHeaderRewrite.SetText(plugin.HostHeaderRewrite)
+	pf.httpUser.SetPlaceHolder("HTTP 认证用户名")
+	pf.httpUser.SetText(plugin.HTTPUser)
+	pf.httpPassword.SetPlaceHolder("HTTP 认证密码")
+	pf.httpPassword.SetText(plugin.HTTPPassword)
+	pf.username.SetPlaceHolder("用户名")
+	pf.username.SetText(plugin.Username)
+	pf.password.SetPlaceHolder("密码")
+	pf.password.SetText(plugin.Password)
+	pf.requestHeaders.SetPlaceHolder("追加请求头，每行一个，如 X-From-Where: frp")
+	pf.requestHeaders.SetText(formatHeaders(plugin.RequestHeaders.Set))
+
+	pf.box = container.NewVBox(
+		widget.NewLabel("插件"),
+		pf.typ,
+		pf.unixPath,
+		pf.localPath,
+		pf.stripPrefix,
+		pf.localAddr,
+		pf.crtPath,
+		pf.keyPath,
+		pf.hostHeaderRewrite,
+		pf.httpUser,
+		pf.httpPassword,
+		pf.username,
+		pf.password,
+		pf.requestHeaders,
+	)
+	if plugin.Type == "" {
+		pf.typ.SetSelected(noPlugin)
+	} else {
+		setSelectOption(pf.typ, plugin.Type)
+	}
+	pf.showFieldsFor(pf.selected())
+	pf.typ.OnChanged = func(string) {
+		pf.showFieldsFor(pf.selected())
+		onChanged()
+	}
+	return pf
+}
+
+// selected 返回选中的插件类型，未使用插件时为空
+func (pf *pluginForm) selected() string {
+	if pf.typ.Selected == noPlugin {
+		return ""
+	}
+	return pf.typ.Selected
+}
+
+// showFieldsFor 按插件类型显示对应的参数
+func (pf *pluginForm) showFieldsFor(typ string) {
+	setVisible(pf.unixPath, typ == "unix_domain_socket")
+	setVisible(pf.localPath, typ == "static_file")
+	setVisible(pf.stripPrefix, typ == "static_file")
+	setVisible(pf.localAddr, typ == "https2http" || typ == "http2https")
+	setVisible(pf.crtPath, typ == "https2http")
+	setVisible(pf.keyPath, typ == "https2http")
+	setVisible(pf.hostHeaderRewrite, typ == "https2http" || typ == "http2https")
+	setVisible(pf.requestHeaders, typ == "https2http" || typ == "http2https")
+	setVisible(pf.httpUser, typ == "http_proxy" || typ == "static_file")
+	setVisible(pf.httpPassword, typ == "http_proxy" || typ == "static_file")
+	setVisible(pf.username, typ == "socks5")
+	setVisible(pf.password, typ == "socks5")
+}
+
+// config 按插件类型取出对应参数
+func (pf *pluginForm) config(field string, errs *ValidationErrors) *ClientPlugin {
+	plugin := &ClientPlugin{Type: pf.selected()}
+	switch plugin.Type {
+	case "unix_domain_socket":
+		plugin.UnixPath = strings.TrimSpace(pf.unixPath.Text)
+	case "http_proxy":
+		plugin.HTTPUser = strings.TrimSpace(pf.httpUser.Text)
+		plugin.HTTPPassword = pf.httpPassword.Text
+	case "socks5":
+		plugin.Username = strings.TrimSpace(pf.username.Text)
+		plugin.Password = pf.password.Text
+	case "static_file":
+		plugin.LocalPath = strings.TrimSpace(pf.localPath.Text)
+		plugin.StripPrefix = strings.TrimSpace(pf.stripPrefix.Text)
+		plugin.HTTPUser = strings.TrimSpace(pf.httpUser.Text)
+		plugin.HTTPPassword = pf.httpPassword.Text
+	case "https2http", "http2https":
+		plugin.LocalAddr = strings.TrimSpace(pf.localAddr.Text)
+		plugin.HostHeaderRewrite = strings.TrimSpace(pf.hostHeaderRewrite.Text)
+		if plugin.Type == "https2http" {
+			plugin.CrtPath = strings.TrimSpace(pf.crtPath.Text)
+			plugin.KeyPath = strings.TrimSpace(pf.keyPath.Text)
+		}
+		headers, err := parseHeaders(pf.requestHeaders.Text)
+		if err != nil {
+			*errs = append(*errs, FieldError{Field: field + ".requestHeaders", Msg: err.Error()})
+		}
+		plugin.RequestHeaders.Set = headers
+	}
+	return plugin
+}
+
+func (pf *pluginForm) fieldEntries() map[string]*widget.Entry {
+	return map[string]*widget.Entry{
+		"unixPath":          pf.unixPath,
+		"localPath":         pf.localPath,
+		"stripPrefix":       pf.stripPrefix,
+		"localAddr":         pf.localAddr,
+		"crtPath":           pf.crtPath,
+		"keyPath":           pf.keyPath,
+		"hostHeaderRewrite": pf.hostHeaderRewrite,
+		"httpUser":          pf.httpUser,
+		"httpPassword":      pf.httpPassword,
+		"username":          pf.username,
+		"password":          pf.password,
+		"requestHeaders":    pf.requestHeaders,
+	}
+}
+
+// healthCheckForm 代理健康检查的表单项
+type healthCheckForm struct {
+	box             *fyne.Container
+	typ             *widget.Select
+	path            *widget.Entry
+	timeoutSeconds  *widget.Entry
+	maxFailed       *widget.Entry
+	intervalSeconds *widget.Entry
+	optionsBox      *fyne.Container
+}
+
+const noHealthCheck = "不检查"
+
+func newHealthCheckForm(hc HealthCheckConfig) *healthCheckForm {
+	hf := &healthCheckForm{
+		typ:             widget.NewSelect([]string{noHealthCheck, "tcp", "http"}, nil),
+		path:            widget.NewEntry(),
+		timeoutSeconds:  widget.NewEntry(),
+		maxFailed:       widget.NewEntry(),
+		intervalSeconds: widget.NewEntry(),
+	}
+	hf.typ.PlaceHolder = "健康检查"
+	hf.path.SetPlaceHolder("检查路径，如 /status")
+	hf.path.SetText(hc.Path)
+	hf.timeoutSeconds.SetPlaceHolder("超时时间 (秒，默认 3)")
+	hf.maxFailed.SetPlaceHolder("连续失败次数 (默认 1)")
+	hf.intervalSeconds.SetPlaceHolder("检查间隔 (秒，默认 10)")
+	for entry, n := range map[*widget.Entry]int{
+		hf.timeoutSeconds:  hc.TimeoutSeconds,
+		hf.maxFailed:       hc.MaxFailed,
+		hf.intervalSeconds: hc.IntervalSeconds,
+	} {
+		if n != 0 {
+			entry.SetText(strconv.Itoa(n))
+		}
+	}
+	hf.optionsBox = container.NewVBox(hf.timeoutSeconds, hf.maxFailed, hf.intervalSeconds)
+
+	hf.box = container.NewVBox(
+		widget.NewLabel("健康检查"),
+		hf.typ,
+		hf.path,
+		hf.optionsBox,
+	)
+	hf.typ.OnChanged = func(string) {
+		typ := hf.selected()
+		setVisible(hf.path, typ == "http")
+		setVisible(hf.optionsBox, typ != "")
+	}
+	if hc.Type == "" {
+		hf.typ.SetSelected(noHealthCheck)
+	} else {
+		setSelectOption(hf.typ, hc.Type)
+	}
+	return hf
+}
+
+// selected 返回选中的检查类型，不检查时为空
+func (hf *healthCheckForm) selected() string {
+	if hf.typ.Selected == noHealthCheck {
+		return ""
+	}
+	return hf.typ.Selected
+}
+
+func (hf *healthCheckForm) config(field string, number func(entry *widget.Entry, field string) int) HealthCheckConfig {
+	hc := HealthCheckConfig{Type: hf.selected()}
+	if hc.Type == "" {
+		return hc
+	}
+	hc.TimeoutSeconds = number(hf.timeoutSeconds, field+".timeoutSeconds")
+	hc.MaxFailed = number(hf.maxFailed, field+".maxFailed")
+	hc.IntervalSeconds = number(hf.intervalSeconds, field+".intervalSeconds")
+	if hc.Type == "http" {
+		hc.Path = strings.TrimSpace(hf.path.Text)
+	}
+	return hc
+}
+
+func (hf *healthCheckForm) fieldEntries() map[string]*widget.Entry {
+	return map[string]*widget.Entry{
+		"path":            hf.path,
+		"timeoutSeconds":  hf.timeoutSeconds,
+		"maxFailed":       hf.maxFailed,
+		"intervalSeconds": hf.intervalSeconds,
+	}
+}
+
+// rangeMode 是否按端口范围展开，只对未使用插件的 tcp/udp 代理生效
+func (pf *proxyForm) rangeMode() bool {
+	typ := pf.typ.Selected
+	return pf.portRange.Checked && pf.plugin.selected() == "" && (typ == "tcp" || typ == "udp")
+}
+
+// checkPort 输入时即时检查端口，端口范围模式下检查范围格式
+func (pf *proxyForm) checkPort(f *configForm, entry *widget.Entry, text string) {
+	if pf.rangeMode() {
+		_, err := parsePortRanges(text)
+		f.markInvalid(entry, err == nil, fmt.Sprint(err))
+		return
+	}
+	f.markInvalid(entry, validatePort(text), "端口范围应为 0-65535")
+}
+
+// config 将表单转换为配置并做完整校验，出错的输入框会被标红
+func (f *configForm) config() (*ClientConfig, error) {
+	f.clearInvalid()
+	var errs ValidationErrors
+	number := func(entry *widget.Entry, field string) int {
+		text := strings.TrimSpace(entry.Text)
+		if text == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			errs = append(errs, FieldError{Field: field, Msg: "必须是数字"})
+		}
+		return n
+	}
+
+	extra, err := decodeExtra(f.extra.Text)
+	if err != nil {
+		return nil, fmt.Errorf("其他配置项: %v", err)
+	}
+	cfg := &ClientConfig{
+		ServerAddr: trimBrackets(strings.TrimSpace(f.serverAddr.Text)),
+		ServerPort: number(f.serverPort, "serverPort"),
+		Auth:       f.auth.config(&errs),
+		Transport:  f.transport.config(number),
+		Extra:      extra,
+	}
+	envs, err := parseParams(f.envs.Text)
+	if err != nil {
+		errs = append(errs, FieldError{Field: "envs", Msg: err.Error()})
+	}
+	cfg.Envs = envs
+	cfg.Includes = splitList(f.includes.Text)
+	if f.fragment {
+		// 片段不写入公共配置
+		cfg = &ClientConfig{Extra: extra}
+	}
+	for i, vf := range f.visitors {
+		field := fmt.Sprintf("visitors[%d]", i)
+		extra, err := decodeExtra(vf.extra.Text)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 个 visitor 的其他配置项: %v", i+1, err)
+		}
+		cfg.Visitors = append(cfg.Visitors, Visitor{
+			Name:           strings.TrimSpace(vf.name.Text),
+			Type:           vf.typ.Selected,
+			ServerName:     strings.TrimSpace(vf.serverName.Text),
+			SecretKey:      vf.secretKey.Text,
+			BindAddr:       strings.TrimSpace(vf.bindAddr.Text),
+			BindPort:       number(vf.bindPort, field+".bindPort"),
+			KeepTunnelOpen: vf.typ.Selected == "xtcp" && vf.keepTunnelOpen.Checked,
+			Extra:          extra,
+		})
+	}
+	// owners 记录每个生成的代理来自第几个表单项，端口范围会展开为多个代理
+	var owners []int
+	for i, pf := range f.proxies {
+		field := fmt.Sprintf("proxies[%d]", i)
+		extra, err := decodeExtra(pf.extra.Text)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 个 proxy 的其他配置项: %v", i+1, err)
+		}
+		p := Proxy{
+			Name: strings.TrimSpace(pf.name.Text),
+			Type: pf.typ.Selected,
+			Transport: ProxyTransport{
+				UseEncryption:      pf.useEncryption.Checked,
+				UseCompression:     pf.useCompression.Checked,
+				BandwidthLimit:     strings.TrimSpace(pf.bandwidthLimit.Text),
+				BandwidthLimitMode: pf.bandwidthLimitMode.Selected,
+			},
+			Extra: extra,
+		}
+		rangeMode := pf.rangeMode()
+		if pf.plugin.selected() != "" {
+			p.Plugin = pf.plugin.config(field+".plugin", &errs)
+		} else {
+			p.LocalIP = strings.TrimSpace(pf.localAddr.Text)
+			if !rangeMode {
+				p.LocalPort = number(pf.localPort, field+".localPort")
+			}
+			if p.Type != "udp" && p.Type != "sudp" {
+				p.HealthCheck = pf.healthCheck.config(field+".healthCheck", number)
+			}
+		}
+		if p.Type == "tcp" || p.Type == "http" {
+			p.LoadBalancer.Group = strings.TrimSpace(pf.group.Text)
+			p.LoadBalancer.GroupKey = pf.groupKey.Text
+		}
+		switch p.Type {
+		case "xtcp", "stcp", "sudp":
+			p.SecretKey = pf.secretKey.Text
+		case "http", "https", "tcpmux":
+			p.CustomDomains = splitList(pf.customDomains.Text)
+			p.SubDomain = strings.TrimSpace(pf.subdomain.Text)
+			if p.Type != "https" {
+				p.HTTPUser = strings.TrimSpace(pf.httpUser.Text)
+				p.HTTPPassword = pf.httpPassword.Text
+			}
+			if p.Type == "tcpmux" {
+				p.Multiplexer = pf.multiplexer.Selected
+				p.RouteByHTTPUser = strings.TrimSpace(pf.routeByHTTPUser.Text)
+			}
+			if p.Type == "http" {
+				p.Locations = splitList(pf.locations.Text)
+				p.HostHeaderRewrite = strings.TrimSpace(pf.hostHeaderRewrite.Text)
+				headers, err := parseHeaders(pf.requestHeaders.Text)
+				if err != nil {
+					errs = append(errs, FieldError{Field: field + ".requestHeaders", Msg: err.Error()})
+				}
+				p.RequestHeaders.Set = headers
+			}
+		default:
+			if !rangeMode {
+				p.RemotePort = number(pf.remotePort, field+".remotePort")
+			}
+		}
+
+		if rangeMode {
+			expanded, ok := f.expandPortRange(p, pf, field, &errs)
+			if !ok {
+				continue
+			}
+			for range expanded {
+				owners = append(owners, i)
+			}
+			cfg.Proxies = append(cfg.Proxies, expanded...)
+			continue
+		}
+		owners = append(owners, i)
+		cfg.Proxies = append(cfg.Proxies, p)
+	}
+
+	cfg.Options.EncryptAllProxies = f.encryptAll.Checked
+	cfg.Options.apply(cfg)
+
+	// 模板要求填写的字段，移除的代理不再检查
+	var missing ValidationErrors
+	for field, entry := range f.fieldEntries() {
+		if f.required[entry] && strings.TrimSpace(entry.Text) == "" {
+			missing = append(missing, FieldError{Field: field, Msg: "请填写模板中的必填项"})
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i].Field < missing[j].Field })
+	errs = append(errs, missing...)
+
+	// 无法解析的数字已经报错，不再重复报告同一字段
+	reported := map[string]bool{}
+	for _, e := range errs {
+		reported[e.Field] = true
+	}
+	for _, e := range f.validate(cfg) {
+		e.Field = formField(e.Field, owners)
+		if !reported[e.Field] {
+			errs = append(errs, e)
+		}
+	}
+	if len(errs) > 0 {
+		entries := f.fieldEntries()
+		for _, e := range errs {
+			if entry, ok := entries[e.Field]; ok {
+				f.markInvalid(entry, false, e.Msg)
+			}
+		}
+		return nil, errs
+	}
+	return cfg, nil
+}
+
+// expandPortRange 解析端口范围表单项并展开，出错时记录到 errs
+func (f *configForm) expandPortRange(p Proxy, pf *proxyForm, field string, errs *ValidationErrors) ([]Proxy, bool) {
+	localPorts, err := parsePortRanges(pf.localPort.Text)
+	if err != nil {
+		*errs = append(*errs, FieldError{Field: field + ".localPort", Msg: err.Error()})
+	}
+	remotePorts, err2 := parsePortRanges(pf.remotePort.Text)
+	if err2 != nil {
+		*errs = append(*errs, FieldError{Field: field + ".remotePort", Msg: err2.Error()})
+	}
+	if p.Name == "" {
+		*errs = append(*errs, FieldError{Field: field + ".name", Msg: "名称不能为空"})
+	}
+	if err != nil || err2 != nil || p.Name == "" {
+		return nil, false
+	}
+	expanded, err := expandPortRange(p, localPorts, remotePorts)
+	if err != nil {
+		*errs = append(*errs, FieldError{Field: field + ".remotePort", Msg: err.Error()})
+		return nil, false
+	}
+	return expanded, true
+}
+
+// formField 把生成配置中的代理下标换算回表单中的下标
+func formField(field string, owners []int) string {
+	var index int
+	if _, err := fmt.Sscanf(field, "proxies[%d]", &index); err != nil || index >= len(owners) {
+		return field
+	}
+	prefix := fmt.Sprintf("proxies[%d]", index)
+	return fmt.Sprintf("proxies[%d]", owners[index]) + strings.TrimPrefix(field, prefix)
+}
+
+// fieldEntries 返回字段路径到输入框的映射，用于把校验错误标记到对应输入框
+func (f *configForm) fieldEntries() map[string]*widget.Entry {
+	entries := map[string]*widget.Entry{
+		"serverAddr": f.serverAddr,
+		"serverPort": f.serverPort,
+		"envs":       f.envs,
+		"includes":   f.includes,
+	}
+	for name, entry := range f.auth.fieldEntries() {
+		entries["auth."+name] = entry
+	}
+	for name, entry := range f.transport.fieldEntries() {
+		entries["transport."+name] = entry
+	}
+	for i, vf := range f.visitors {
+		field := fmt.Sprintf("visitors[%d]", i)
+		entries[field+".name"] = vf.name
+		entries[field+".serverName"] = vf.serverName
+		entries[field+".secretKey"] = vf.secretKey
+		entries[field+".bindAddr"] = vf.bindAddr
+		entries[field+".bindPort"] = vf.bindPort
+	}
+	for i, pf := range f.proxies {
+		field := fmt.Sprintf("proxies[%d]", i)
+		entries[field+".name"] = pf.name
+		entries[field+".localIP"] = pf.localAddr
+		entries[field+".localPort"] = pf.localPort
+		entries[field+".remotePort"] = pf.remotePort
+		entries[field+".secretKey"] = pf.secretKey
+		entries[field+".customDomains"] = pf.customDomains
+		entries[field+".subdomain"] = pf.subdomain
+		entries[field+".locations"] = pf.locations
+		entries[field+".hostHeaderRewrite"] = pf.hostHeaderRewrite
+		entries[field+".httpUser"] = pf.httpUser
+		entries[field+".httpPassword"] = pf.httpPassword
+		entries[field+".requestHeaders"] = pf.requestHeaders
+		entries[field+".routeByHTTPUser"] = pf.routeByHTTPUser
+		entries[field+".transport.bandwidthLimit"] = pf.bandwidthLimit
+		entries[field+".loadBalancer.group"] = pf.group
+		entries[field+".loadBalancer.groupKey"] = pf.groupKey
+		for name, entry := range pf.healthCheck.fieldEntries() {
+			entries[field+".healthCheck."+name] = entry
+		}
+		for name, entry := range pf.plugin.fieldEntries() {
+			entries[field+".plugin."+name] = entry
+		}
+	}
+	return entries
+}
+
+// clearInvalid 清除上一次校验留下的错误标记
+func (f *configForm) clearInvalid() {
+	for _, entry := range f.fieldEntries() {
+		entry.Validator = nil
+		entry.SetValidationError(nil)
+	}
+	f.errorLabel.Hide()
+}
+
+// setSelectOption 选中 value，表单未列出的取值会被追加到选项中以免保存时丢失
+func setSelectOption(sel *widget.Select, value string) {
+	if value == "" {
+		return
+	}
+	for _, option := range sel.Options {
+		if option == value {
+			sel.SetSelected(value)
+			return
+		}
+	}
+	sel.Options = append(sel.Options, value)
+	sel.SetSelected(value)
+}
+
+// newExtraEntry 用原始 TOML 展示表单不认识的配置项
+func newExtraEntry(extra map[string]any) *widget.Entry {
+	entry := widget.NewMultiLineEntry()
+	entry.SetPlaceHolder("表单未包含的配置项，按 TOML 格式填写")
+	if text, err := encodeExtra(extra); err == nil {
+		entry.SetText(strings.TrimSpace(text))
+	}
+	return entry
+}
+
+// newExtraAccordion 把原始配置项收进折叠面板，有内容时默认展开
+func newExtraAccordion(entry *widget.Entry) *widget.Accordion {
+	acc := widget.NewAccordion(widget.NewAccordionItem("其他配置项 (TOML)", entry))
+	if entry.Text != "" {
+		acc.Open(0)
+	}
+	return acc
+}
+
+// newEnvAccordion 环境变量的折叠面板，有内容时默认展开
+func newEnvAccordion(entry *widget.Entry) *widget.Accordion {
+	acc := widget.NewAccordion(widget.NewAccordionItem("环境变量", entry))
+	if entry.Text != "" {
+		acc.Open(0)
+	}
+	return acc
+}
+
+func setVisible(obj fyne.CanvasObject, visible bool) {
+	if visible {
+		obj.Show()
+	} else {
+		obj.Hide()
+	}
+}
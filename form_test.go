@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+)
+
+// 表单只编辑认识的字段，已知表下的未知配置项要原样写回
+func TestFormKeepsUnknownKeys(t *testing.T) {
+	test.NewTempApp(t)
+	text := `serverAddr = "frp.example.com"
+serverPort = 7000
+loginFailExit = false
+
+[auth]
+method = "token"
+token = "secret"
+
+[log]
+to = "./frpc.log"
+level = "info"
+
+[transport]
+protocol = "tcp"
+poolCount = 5
+dialServerTimeout = 10
+dialServerKeepalive = 7200
+heartbeatInterval = 30
+
+[transport.tls]
+enable = true
+disableCustomTLSFirstByte = false
+
+[[proxies]]
+name = "ssh"
+type = "tcp"
+localIP = "127.0.0.1"
+localPort = 22
+remotePort = 6000
+
+[proxies.transport]
+useEncryption = true
+useCompression = true
+proxyProtocolVersion = "v2"
+bandwidthLimit = "1MB"
+
+[[proxies]]
+name = "web"
+type = "http"
+localPort = 8080
+customDomains = ["www.example.com"]
+
+[proxies.transport]
+proxyProtocolVersion = "v1"
+
+[proxies.metadatas]
+owner = "ops"
+`
+	want, err := decodeConfig([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := newConfigForm(want).config()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := encodeConfig(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err = decodeConfig(data); err != nil {
+		t.Fatalf("%v\n%s", err, data)
+	}
+
+	wantExtra := map[string]any{
+		"loginFailExit": false,
+		"log":           map[string]any{"to": "./frpc.log", "level": "info"},
+		"transport": map[string]any{
+			"dialServerTimeout":   int64(10),
+			"dialServerKeepalive": int64(7200),
+			"tls":                 map[string]any{"disableCustomTLSFirstByte": false},
+		},
+	}
+	if !reflect.DeepEqual(got.Extra, wantExtra) {
+		t.Errorf("顶层未知配置项不一致: %#v\n%s", got.Extra, data)
+	}
+	wantProxies := []map[string]any{
+		{"transport": map[string]any{"proxyProtocolVersion": "v2"}},
+		{"transport": map[string]any{"proxyProtocolVersion": "v1"}, "metadatas": map[string]any{"owner": "ops"}},
+	}
+	for i, p := range got.Proxies {
+		if !reflect.DeepEqual(p.Extra, wantProxies[i]) {
+			t.Errorf("第 %d 个代理未知配置项不一致: %#v\n%s", i+1, p.Extra, data)
+		}
+	}
+	if got.Transport.PoolCount != 5 || (got.Transport.TLS.Enable != nil && !*got.Transport.TLS.Enable) || !got.Proxies[0].Transport.UseCompression {
+		t.Errorf("表单字段丢失\n%s", data)
+	}
+}
+
+// 保存为模板时替换掉的密钥，从模板新建时都要能在表单中标为必填
+func TestTemplateSecretsAreRequiredInForm(t *testing.T) {
+	test.NewTempApp(t)
+	cfg := &ClientConfig{
+		ServerAddr: "frp.example.com",
+		ServerPort: 7000,
+		Auth:       AuthConfig{Method: "token", Token: "secret"},
+		Visitors:   []Visitor{{Name: "v", Type: "stcp", ServerName: "ssh", SecretKey: "key", BindPort: 6000}},
+		Proxies: []Proxy{
+			{Name: "web", Type: "http", LocalPort: 80, CustomDomains: []string{"a.example.com"},
+				HTTPUser: "admin", HTTPPassword: "pass", LoadBalancer: LoadBalancerConfig{Group: "web", GroupKey: "gk"}},
+			{Name: "stcp", Type: "stcp", LocalPort: 22, SecretKey: "key"},
+			{Name: "proxy", Type: "tcp", RemotePort: 6001, Plugin: &ClientPlugin{Type: "http_proxy", HTTPUser: "u", HTTPPassword: "p"}},
+			{Name: "socks", Type: "tcp", RemotePort: 6002, Plugin: &ClientPlugin{Type: "socks5", Username: "u", Password: "p"}},
+		},
+	}
+	path := filepath.Join(t.TempDir(), "t.toml")
+	if err := saveTemplate(path, cfg); err != nil {
+		t.Fatal(err)
+	}
+	loaded, fields, err := loadTemplate(configTemplate{name: "t", path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 8 {
+		t.Fatalf("模板必填项数量不对: %v", fields)
+	}
+	f := newConfigForm(loaded)
+	f.markRequired(fields)
+	entries := f.fieldEntries()
+	for _, field := range fields {
+		if _, ok := entries[field]; !ok {
+			t.Errorf("表单中没有 %s 对应的输入框", field)
+		}
+	}
+
+	// 未填写的必填项保存时要报错
+	_, err = f.config()
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("未填写必填项时应保存失败: %v", err)
+	}
+	reported := map[string]bool{}
+	for _, e := range errs {
+		reported[e.Field] = true
+	}
+	for _, field := range fields {
+		if !reported[field] {
+			t.Errorf("%s 未填写却没有报错", field)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// frpc 支持的配置文件格式
+const (
+	formatTOML = "toml"
+	formatYAML = "yaml"
+	formatJSON = "json"
+)
+
+var configFormats = []string{formatTOML, formatYAML, formatJSON}
+
+// configFormat 根据扩展名判断配置格式，无法识别时返回空字符串
+func configFormat(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".toml":
+		return formatTOML
+	case ".yaml", ".yml":
+		return formatYAML
+	case ".json":
+		return formatJSON
+	}
+	return ""
+}
+
+// isConfigFile 判断文件是否为 frpc 可以加载的配置文件
+func isConfigFile(name string) bool {
+	return configFormat(name) != ""
+}
+
+// detectFormat 根据内容猜测配置格式，用于没有文件名的 Base64 导入
+func detectFormat(data []byte) string {
+	var v map[string]any
+	if _, err := toml.Decode(string(data), &v); err == nil {
+		return formatTOML
+	}
+	if json.Valid(data) {
+		return formatJSON
+	}
+	if err := yaml.Unmarshal(data, &v); err == nil && v != nil {
+		return formatYAML
+	}
+	return formatTOML
+}
+
+// decodeConfigAs 按指定格式解析配置
+func decodeConfigAs(format string, data []byte) (*ClientConfig, error) {
+	if format != formatYAML && format != formatJSON {
+		return decodeConfig(data)
+	}
+	raw, err := decodeRawAs(format, data)
+	if err != nil {
+		return nil, err
+	}
+	return configFromMap(raw)
+}
+
+// decodeRawAs 按指定格式把配置解析为通用的 map
+func decodeRawAs(format string, data []byte) (map[string]any, error) {
+	var raw map[string]any
+	switch format {
+	case formatYAML:
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("解析 YAML 配置失败: %v", err)
+		}
+	case formatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("解析 JSON 配置失败: %v", err)
+		}
+	default:
+		if _, err := toml.Decode(string(data), &raw); err != nil {
+			return nil, fmt.Errorf("解析配置失败: %v", err)
+		}
+		return raw, nil
+	}
+	normalized, err := normalizeValue(raw)
+	if err != nil {
+		return nil, fmt.Errorf("解析配置失败: %v", err)
+	}
+	m, _ := normalized.(map[string]any)
+	return m, nil
+}
+
+// encodeConfigAs 按指定格式编码配置
+func encodeConfigAs(format string, cfg *ClientConfig) ([]byte, error) {
+	if format != formatYAML && format != formatJSON {
+		return encodeConfig(cfg)
+	}
+	m, err := configToMap(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if format == formatJSON {
+		data, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("编码配置失败: %v", err)
+		}
+		return append(data, '\n'), nil
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(m); err != nil {
+		return nil, fmt.Errorf("编码配置失败: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// normalizeValue 将 YAML/JSON 解码出的值转换为 TOML 编码器能处理的类型：
+// JSON 数字转为 int64 或 float64，元素全是表的数组转为 []map[string]any
+func normalizeValue(v any) (any, error) {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			// TOML 没有空值，直接丢弃未赋值的键
+			if item == nil {
+				delete(val, k)
+				continue
+			}
+			n, err := normalizeValue(item)
+			if err != nil {
+				return nil, err
+			}
+			val[k] = n
+		}
+		return val, nil
+	case []any:
+		tables := make([]map[string]any, 0, len(val))
+		for i, item := range val {
+			n, err := normalizeValue(item)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = n
+			if m, ok := n.(map[string]any); ok {
+				tables = append(tables, m)
+			}
+		}
+		if len(val) > 0 && len(tables) == len(val) {
+			return tables, nil
+		}
+		return val, nil
+	case json.Number:
+		if n, err := val.Int64(); err == nil {
+			return n, nil
+		}
+		return val.Float64()
+	case int:
+		return int64(val), nil
+	case nil:
+		return nil, fmt.Errorf("数组中不支持空值")
+	}
+	return v, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// groupMember 负载均衡分组中的一个代理及其所在的配置文件
+type groupMember struct {
+	file  string
+	proxy Proxy
+}
+
+// proxyGroup 所有配置中同名的负载均衡分组
+type proxyGroup struct {
+	name    string
+	members []groupMember
+}
+
+// collectGroups 读取 dir 下的配置文件，按分组名汇总代理，无法解析的文件作为错误返回
+func collectGroups(dir string, files []string) ([]proxyGroup, []error) {
+	var errs []error
+	byName := map[string]*proxyGroup{}
+	for _, file := range files {
+		cfg, err := loadConfig(filepath.Join(dir, file))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %v", file, err))
+			continue
+		}
+		for _, p := range cfg.Proxies {
+			name := p.LoadBalancer.Group
+			if name == "" {
+				continue
+			}
+			g, ok := byName[name]
+			if !ok {
+				g = &proxyGroup{name: name}
+				byName[name] = g
+			}
+			g.members = append(g.members, groupMember{file: file, proxy: p})
+		}
+	}
+
+	groups := make([]proxyGroup, 0, len(byName))
+	for _, g := range byName {
+		groups = append(groups, *g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].name < groups[j].name })
+	return groups, errs
+}
+
+// problems 检查分组内代理的类型、分组密钥和 tcp 远程端口是否一致
+func (g proxyGroup) problems() []string {
+	var problems []string
+	first := g.members[0].proxy
+	for _, m := range g.members[1:] {
+		p := m.proxy
+		if p.Type != first.Type {
+			problems = append(problems, fmt.Sprintf("%s 中的 %s 类型为 %s，与 %s 不一致", m.file, p.Name, p.Type, first.Type))
+			continue
+		}
+		// 密钥本身不展示，只提示不一致
+		if p.LoadBalancer.GroupKey != first.LoadBalancer.GroupKey {
+			problems = append(problems, fmt.Sprintf("%s 中的 %s 分组密钥不一致", m.file, p.Name))
+		}
+		if p.Type == "tcp" && p.RemotePort != first.RemotePort {
+			problems = append(problems, fmt.Sprintf("%s 中的 %s 远程端口为 %d，与 %d 不一致", m.file, p.Name, p.RemotePort, first.RemotePort))
+		}
+	}
+	return problems
+}
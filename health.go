@@ -0,0 +1,66 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// frpc 在健康检查状态变化时输出 "[代理名] health check failed/success"
+var healthLogPattern = regexp.MustCompile(`\[([^\[\]]+)\] health check (failed|success)`)
+
+// healthEvent 从 frpc 日志中解析出的健康检查结果
+type healthEvent struct {
+	proxy string
+	ok    bool
+}
+
+// parseHealthEvent 解析一行 frpc 日志，不是健康检查日志时返回 false
+func parseHealthEvent(line string) (healthEvent, bool) {
+	m := healthLogPattern.FindStringSubmatch(line)
+	if m == nil {
+		return healthEvent{}, false
+	}
+	return healthEvent{proxy: m[1], ok: m[2] == "success"}, true
+}
+
+// healthStatus 记录各代理最近一次健康检查是否失败，日志协程会并发更新
+type healthStatus struct {
+	mu     sync.Mutex
+	failed map[string]bool
+}
+
+func (h *healthStatus) update(ev healthEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.failed == nil {
+		h.failed = map[string]bool{}
+	}
+	if ev.ok {
+		delete(h.failed, ev.proxy)
+	} else {
+		h.failed[ev.proxy] = true
+	}
+}
+
+func (h *healthStatus) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failed = nil
+}
+
+// summary 返回界面上显示的健康检查摘要，全部正常时为空
+func (h *healthStatus) summary() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.failed) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(h.failed))
+	for name := range h.failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "健康检查失败: " + strings.Join(names, ", ")
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// includedFiles 按 frpc 的方式展开配置中的 includes，返回相对 dir 的片段路径。
+// 启动器在配置所在的 dir 中运行 frpc，相对路径也相对 dir 解析；通配符只作用于文件名，
+// 与 frpc 一样，目录不存在时报错
+func includedFiles(dir string, cfg *ClientConfig) ([]string, error) {
+	var files []string
+	seen := map[string]bool{}
+	for _, include := range cfg.Includes {
+		pattern := include
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		if _, err := filepath.Match(filepath.Base(pattern), ""); err != nil {
+			return nil, fmt.Errorf("无效的 includes 路径 %q: %v", include, err)
+		}
+		entries, err := os.ReadDir(filepath.Dir(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("读取 includes 目录 %q 失败: %v", filepath.Dir(include), err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			if ok, _ := filepath.Match(filepath.Base(pattern), entry.Name()); !ok {
+				continue
+			}
+			match := filepath.Join(filepath.Dir(pattern), entry.Name())
+			rel, err := filepath.Rel(dir, match)
+			if err != nil {
+				rel = match
+			}
+			if !seen[rel] && isConfigFile(rel) {
+				seen[rel] = true
+				files = append(files, rel)
+			}
+		}
+	}
+	return files, nil
+}
+
+// listProfiles 把 dir 中的配置文件和它们引用的片段排成列表，片段紧跟在主配置之后，
+// 返回的 parents 记录每个片段所属的主配置
+func listProfiles(dir string, names []string) ([]string, map[string]string) {
+	parents := map[string]string{}
+	children := map[string][]string{}
+	for _, name := range names {
+		cfg, err := loadConfig(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		fragments, err := includedFiles(dir, cfg)
+		if err != nil {
+			continue
+		}
+		for _, fragment := range fragments {
+			// 同一个片段被多个配置引用时归到第一个
+			if _, ok := parents[fragment]; ok || fragment == name {
+				continue
+			}
+			parents[fragment] = name
+			children[name] = append(children[name], fragment)
+		}
+	}
+
+	var files []string
+	for _, name := range names {
+		if _, ok := parents[name]; ok {
+			continue
+		}
+		files = append(files, name)
+		files = append(files, children[name]...)
+	}
+	return files, parents
+}
+
+// profilePart 合并后的某一项来自哪个文件的第几项
+type profilePart struct {
+	file  string
+	index int
+}
+
+// mergedProfile 主配置与其 includes 片段合并后的结果
+type mergedProfile struct {
+	mainFile string
+	cfg      *ClientConfig
+	visitors []profilePart
+	proxies  []profilePart
+}
+
+// mergeIncludes 按 frpc 的方式把片段中的 visitor 和代理追加到主配置之后，
+// override 中的片段使用尚未保存的内容
+func mergeIncludes(dir, mainFile string, cfg *ClientConfig, override map[string]*ClientConfig) (*mergedProfile, error) {
+	merged := *cfg
+	mp := &mergedProfile{mainFile: mainFile, cfg: &merged}
+	merged.Visitors = nil
+	merged.Proxies = nil
+	add := func(file string, part *ClientConfig) {
+		for i, v := range part.Visitors {
+			merged.Visitors = append(merged.Visitors, v)
+			mp.visitors = append(mp.visitors, profilePart{file: file, index: i})
+		}
+		for i, p := range part.Proxies {
+			merged.Proxies = append(merged.Proxies, p)
+			mp.proxies = append(mp.proxies, profilePart{file: file, index: i})
+		}
+	}
+	add(mainFile, cfg)
+
+	fragments, err := includedFiles(dir, cfg)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range fragments {
+		if file == mainFile {
+			continue
+		}
+		fragment, ok := override[file]
+		if !ok {
+			if fragment, err = loadConfig(filepath.Join(dir, file)); err != nil {
+				return nil, fmt.Errorf("%s: %v", file, err)
+			}
+		}
+		add(file, fragment)
+	}
+	return mp, nil
+}
+
+// validate 校验合并后的配置，错误字段换算为 editing 文件中的位置，其他文件的字段前加上文件名
+func (mp *mergedProfile) validate(editing string) ValidationErrors {
+	errs := validateConfig(mp.cfg)
+	for i := range errs {
+		errs[i].Field = mp.localField(errs[i].Field, editing)
+		// 消息中提到的其他项也换算为所在文件中的位置
+		errs[i].Msg = entryRefPattern.ReplaceAllStringFunc(errs[i].Msg, func(ref string) string {
+			return mp.localField(ref, editing)
+		})
+	}
+	return errs
+}
+
+// entryRefPattern 校验消息中对其他代理或 visitor 的引用
+var entryRefPattern = regexp.MustCompile(`(visitors|proxies)\[[0-9]+\]`)
+
+func (mp *mergedProfile) localField(field, editing string) string {
+	for _, list := range []struct {
+		name  string
+		parts []profilePart
+	}{{"visitors", mp.visitors}, {"proxies", mp.proxies}} {
+		var index int
+		if _, err := fmt.Sscanf(field, list.name+"[%d]", &index); err != nil || index >= len(list.parts) {
+			continue
+		}
+		part := list.parts[index]
+		rest := strings.TrimPrefix(field, fmt.Sprintf("%s[%d]", list.name, index))
+		local := fmt.Sprintf("%s[%d]", list.name, part.index) + rest
+		if part.file == editing {
+			return local
+		}
+		return part.file + ": " + local
+	}
+	// 公共配置属于主配置
+	if editing != mp.mainFile {
+		return mp.mainFile + ": " + field
+	}
+	return field
+}
+
+// validateMerged 把 cfg 作为 mainFile 的内容与其片段合并后校验，editing 为正在编辑的文件
+func validateMerged(dir, mainFile string, cfg *ClientConfig, editing string, override map[string]*ClientConfig) ValidationErrors {
+	mp, err := mergeIncludes(dir, mainFile, cfg, override)
+	if err != nil {
+		return ValidationErrors{{Field: "includes", Msg: err.Error()}}
+	}
+	return mp.validate(editing)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, text string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(text), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIncludedFilesRelativeToConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "confd", "a.toml"), "")
+	writeFile(t, filepath.Join(dir, "confd", "b.toml"), "")
+	writeFile(t, filepath.Join(dir, "confd", "notes.txt"), "")
+	writeFile(t, filepath.Join(dir, "confd", "sub", "c.toml"), "")
+
+	// 通配符只匹配目录中的文件，与 frpc 一致
+	got, err := includedFiles(dir, &ClientConfig{Includes: []string{"./confd/*.toml", "confd/a.toml"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join("confd", "a.toml"), filepath.Join("confd", "b.toml")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("片段 %v，期望 %v", got, want)
+	}
+
+	// 绝对路径同样显示为相对配置目录的路径
+	got, err = includedFiles(dir, &ClientConfig{Includes: []string{filepath.Join(dir, "confd", "b.*")}})
+	if err != nil || !reflect.DeepEqual(got, want[1:]) {
+		t.Errorf("片段 %v，期望 %v: %v", got, want[1:], err)
+	}
+}
+
+func TestIncludedFilesMissingDir(t *testing.T) {
+	dir := t.TempDir()
+	_, err := includedFiles(dir, &ClientConfig{Includes: []string{"./missing/*.toml"}})
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("includes 目录不存在时应报错，得到 %v", err)
+	}
+	// 目录存在但没有匹配的文件不算错误
+	if err := os.Mkdir(filepath.Join(dir, "empty"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := includedFiles(dir, &ClientConfig{Includes: []string{"./empty/*.toml"}}); err != nil || len(got) != 0 {
+		t.Errorf("空目录应没有片段: %v %v", got, err)
+	}
+}
+
+func TestValidateMergedReportsMissingIncludeDir(t *testing.T) {
+	dir := t.TempDir()
+	cfg := validConfig()
+	cfg.Includes = []string{"./confd/*.toml"}
+	errs := validateMerged(dir, "main.toml", cfg, "main.toml", nil)
+	if len(errs) != 1 || errs[0].Field != "includes" {
+		t.Errorf("缺少 includes 目录时应报告 includes 错误，得到 %v", errs)
+	}
+
+	writeFile(t, filepath.Join(dir, "confd", "web.toml"), "[[proxies]]\nname = \"web\"\ntype = \"tcp\"\nlocalPort = 80\nremotePort = 6000\n")
+	writeFile(t, filepath.Join(dir, "confd", "ssh.toml"), "[[proxies]]\nname = \"ssh\"\ntype = \"tcp\"\nlocalPort = 22\nremotePort = 6000\n")
+	errs = validateMerged(dir, "main.toml", cfg, "main.toml", nil)
+	want := filepath.Join("confd", "web.toml") + ": proxies[0].remotePort"
+	if len(errs) != 1 || errs[0].Field != want {
+		t.Errorf("合并片段后应报告远程端口冲突 %s，得到 %v", want, errs)
+	}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// iniSection 旧版 frpc.ini 中的一个段
+type iniSection struct {
+	name string
+	keys []string // 保持键在文件中的顺序
+	vals map[string]string
+}
+
+// isINI 判断导入的内容是否为旧版 INI 配置
+func isINI(name string, data []byte) bool {
+	if strings.EqualFold(filepath.Ext(name), ".ini") {
+		return true
+	}
+	// 能按 TOML 解析的一律当作 TOML，否则看是否有 [common] 段
+	var v map[string]any
+	if _, err := toml.Decode(string(data), &v); err == nil {
+		return false
+	}
+	for _, sec := range parseINI(data) {
+		if sec.name == "common" {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeImported 解析导入的配置，旧版 INI 会被转换为 TOML 结构，同时返回未能转换的键
+func decodeImported(name string, data []byte) (*ClientConfig, []string, error) {
+	format := configFormat(name)
+	if format == "" && isINI(name, data) {
+		return convertINI(data)
+	}
+	if format == "" {
+		format = detectFormat(data)
+	}
+	cfg, err := decodeConfigAs(format, data)
+	return cfg, nil, err
+}
+
+// importMessage 生成导入结果提示，列出未能转换的键
+func importMessage(msg string, skipped []string) string {
+	if len(skipped) == 0 {
+		return msg
+	}
+	return msg + "\n以下 INI 配置项未能转换，请手动检查:\n" + strings.Join(skipped, "\n")
+}
+
+// parseINI 解析 INI 文本，忽略以 ; 或 # 开头的注释
+func parseINI(data []byte) []*iniSection {
+	var (
+		sections []*iniSection
+		cur      *iniSection
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			cur = &iniSection{name: strings.TrimSpace(line[1 : len(line)-1]), vals: map[string]string{}}
+			sections = append(sections, cur)
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok || cur == nil {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if _, exists := cur.vals[key]; !exists {
+			cur.keys = append(cur.keys, key)
+		}
+		cur.vals[key] = strings.TrimSpace(val)
+	}
+	return sections
+}
+
+// iniConverter 记录转换过程中无法识别或取值非法的键
+type iniConverter struct {
+	skipped []string
+}
+
+func (c *iniConverter) skip(section, key, reason string) {
+	c.skipped = append(c.skipped, fmt.Sprintf("[%s] %s: %s", section, key, reason))
+}
+
+func (c *iniConverter) int(section, key, val string) int {
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		c.skip(section, key, "不是有效的数字")
+	}
+	return n
+}
+
+func (c *iniConverter) bool(section, key, val string) bool {
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		c.skip(section, key, "不是有效的布尔值")
+	}
+	return b
+}
+
+// iniCommonExtra 表单之外、可以直接改名的 [common] 键，值为 TOML 键路径
+var iniCommonExtra = map[string]struct {
+	key  toml.Key
+	kind string
+}{
+	"user":            {toml.Key{"user"}, "string"},
+	"login_fail_exit": {toml.Key{"loginFailExit"}, "bool"},
+	"dns_server":      {toml.Key{"dnsServer"}, "string"},
+	"log_file":        {toml.Key{"log", "to"}, "string"},
+	"log_level":       {toml.Key{"log", "level"}, "string"},
+	"log_max_days":    {toml.Key{"log", "maxDays"}, "int"},
+	"admin_addr":      {toml.Key{"webServer", "addr"}, "string"},
+	"admin_port":      {toml.Key{"webServer", "port"}, "int"},
+	"admin_user":      {toml.Key{"webServer", "user"}, "string"},
+	"admin_pwd":       {toml.Key{"webServer", "password"}, "string"},
+	"udp_packet_size": {toml.Key{"udpPacketSize"}, "int"},
+}
+
+// iniProxyExtra 代理段中表单之外、可以直接改名的键
+var iniProxyExtra = map[string]struct {
+	key  toml.Key
+	kind string
+}{
+	"proxy_protocol_version": {toml.Key{"transport", "proxyProtocolVersion"}, "string"},
+}
+
+// convertINI 把旧版 frpc.ini 转换为配置，返回无法转换的键供用户确认
+func convertINI(data []byte) (*ClientConfig, []string, error) {
+	sections := parseINI(data)
+	c := &iniConverter{}
+	cfg := &ClientConfig{}
+
+	var foundCommon bool
+	for _, sec := range sections {
+		if sec.name == "common" {
+			foundCommon = true
+			c.convertCommon(cfg, sec)
+			continue
+		}
+		if sec.vals["role"] == "visitor" {
+			cfg.Visitors = append(cfg.Visitors, c.convertVisitor(sec))
+			continue
+		}
+		proxy := c.convertProxy(sec)
+		// range:name 段按端口范围展开为多个代理
+		if name, ok := strings.CutPrefix(sec.name, "range:"); ok {
+			proxy.Name = name
+			localPorts, err := parsePortRanges(sec.vals["local_port"])
+			if err != nil {
+				c.skip(sec.name, "local_port", err.Error())
+				continue
+			}
+			remotePorts, err := parsePortRanges(sec.vals["remote_port"])
+			if err != nil {
+				c.skip(sec.name, "remote_port", err.Error())
+				continue
+			}
+			expanded, err := expandPortRange(proxy, localPorts, remotePorts)
+			if err != nil {
+				c.skip(sec.name, "remote_port", err.Error())
+				continue
+			}
+			cfg.Proxies = append(cfg.Proxies, expanded...)
+			continue
+		}
+		cfg.Proxies = append(cfg.Proxies, proxy)
+	}
+	if !foundCommon {
+		return nil, nil, fmt.Errorf("INI 配置缺少 [common] 段")
+	}
+	return cfg, c.skipped, nil
+}
+
+func (c *iniConverter) convertCommon(cfg *ClientConfig, sec *iniSection) {
+	for _, key := range sec.keys {
+		val := sec.vals[key]
+		switch key {
+		case "server_addr":
+			cfg.ServerAddr = val
+		case "server_port":
+			cfg.ServerPort = c.int(sec.name, key, val)
+		case "authentication_method":
+			cfg.Auth.Method = val
+		case "token":
+			cfg.Auth.Token = val
+		case "authenticate_heartbeats":
+			if c.bool(sec.name, key, val) {
+				cfg.Auth.AdditionalScopes = append(cfg.Auth.AdditionalScopes, "HeartBeats")
+			}
+		case "authenticate_new_work_conns":
+			if c.bool(sec.name, key, val) {
+				cfg.Auth.AdditionalScopes = append(cfg.Auth.AdditionalScopes, "NewWorkConns")
+			}
+		case "oidc_client_id":
+			cfg.Auth.OIDC.ClientID = val
+		case "oidc_client_secret":
+			cfg.Auth.OIDC.ClientSecret = val
+		case "oidc_audience":
+			cfg.Auth.OIDC.Audience = val
+		case "oidc_scope":
+			cfg.Auth.OIDC.Scope = val
+		case "oidc_token_endpoint_url":
+			cfg.Auth.OIDC.TokenEndpointURL = val
+		case "protocol":
+			cfg.Transport.Protocol = val
+		case "connect_server_local_ip":
+			cfg.Transport.ConnectServerLocalIP = val
+		case "pool_count":
+			cfg.Transport.PoolCount = c.int(sec.name, key, val)
+		case "tcp_mux":
+			b := c.bool(sec.name, key, val)
+			cfg.Transport.TCPMux = &b
+		case "heartbeat_interval":
+			cfg.Transport.HeartbeatInterval = c.int(sec.name, key, val)
+		case "heartbeat_timeout":
+			cfg.Transport.HeartbeatTimeout = c.int(sec.name, key, val)
+		case "tls_enable":
+			b := c.bool(sec.name, key, val)
+			cfg.Transport.TLS.Enable = &b
+		case "tls_cert_file":
+			cfg.Transport.TLS.CertFile = val
+		case "tls_key_file":
+			cfg.Transport.TLS.KeyFile = val
+		case "tls_trusted_ca_file":
+			cfg.Transport.TLS.TrustedCaFile = val
+		case "tls_server_name":
+			cfg.Transport.TLS.ServerName = val
+		default:
+			if strings.HasPrefix(key, "oidc_additional_") {
+				if cfg.Auth.OIDC.AdditionalEndpointParams == nil {
+					cfg.Auth.OIDC.AdditionalEndpointParams = map[string]string{}
+				}
+				cfg.Auth.OIDC.AdditionalEndpointParams[strings.TrimPrefix(key, "oidc_additional_")] = val
+				continue
+			}
+			m, ok := iniCommonExtra[key]
+			if !ok {
+				c.skip(sec.name, key, "没有对应的 TOML 配置项")
+				continue
+			}
+			if cfg.Extra == nil {
+				cfg.Extra = map[string]any{}
+			}
+			setKey(cfg.Extra, m.key, c.value(sec.name, key, val, m.kind))
+		}
+	}
+}
+
+func (c *iniConverter) convertVisitor(sec *iniSection) Visitor {
+	v := Visitor{Name: sec.name}
+	for _, key := range sec.keys {
+		val := sec.vals[key]
+		switch key {
+		case "role":
+		case "type":
+			v.Type = val
+		case "server_name":
+			v.ServerName = val
+		case "sk":
+			v.SecretKey = val
+		case "bind_addr":
+			v.BindAddr = val
+		case "bind_port":
+			v.BindPort = c.int(sec.name, key, val)
+		case "keep_tunnel_open":
+			v.KeepTunnelOpen = c.bool(sec.name, key, val)
+		default:
+			c.skip(sec.name, key, "没有对应的 TOML 配置项")
+		}
+	}
+	return v
+}
+
+func (c *iniConverter) convertProxy(sec *iniSection) Proxy {
+	p := Proxy{Name: sec.name}
+	plugin := &ClientPlugin{}
+	for _, key := range sec.keys {
+		val := sec.vals[key]
+		switch key {
+		case "type":
+			p.Type = val
+		case "local_ip":
+			p.LocalIP = val
+		case "local_port":
+			if !strings.HasPrefix(sec.name, "range:") {
+				p.LocalPort = c.int(sec.name, key, val)
+			}
+		case "remote_port":
+			if !strings.HasPrefix(sec.name, "range:") {
+				p.RemotePort = c.int(sec.name, key, val)
+			}
+		case "sk":
+			p.SecretKey = val
+		case "custom_domains":
+			p.CustomDomains = splitList(val)
+		case "subdomain":
+			p.SubDomain = val
+		case "locations":
+			p.Locations = splitList(val)
+		case "host_header_rewrite":
+			p.HostHeaderRewrite = val
+		case "http_user":
+			p.HTTPUser = val
+		case "http_pwd":
+			p.HTTPPassword = val
+		case "multiplexer":
+			p.Multiplexer = val
+		case "route_by_http_user":
+			p.RouteByHTTPUser = val
+		case "use_encryption":
+			p.Transport.UseEncryption = c.bool(sec.name, key, val)
+		case "use_compression":
+			p.Transport.UseCompression = c.bool(sec.name, key, val)
+		case "bandwidth_limit":
+			p.Transport.BandwidthLimit = val
+		case "bandwidth_limit_mode":
+			p.Transport.BandwidthLimitMode = val
+		case "group":
+			p.LoadBalancer.Group = val
+		case "group_key":
+			p.LoadBalancer.GroupKey = val
+		case "health_check_type":
+			p.HealthCheck.Type = val
+		case "health_check_url":
+			p.HealthCheck.Path = val
+		case "health_check_timeout_s":
+			p.HealthCheck.TimeoutSeconds = c.int(sec.name, key, val)
+		case "health_check_max_failed":
+			p.HealthCheck.MaxFailed = c.int(sec.name, key, val)
+		case "health_check_interval_s":
+			p.HealthCheck.IntervalSeconds = c.int(sec.name, key, val)
+		case "plugin":
+			plugin.Type = val
+		case "plugin_unix_path":
+			plugin.UnixPath = val
+		case "plugin_local_path":
+			plugin.LocalPath = val
+		case "plugin_strip_prefix":
+			plugin.StripPrefix = val
+		case "plugin_local_addr":
+			plugin.LocalAddr = val
+		case "plugin_crt_path":
+			plugin.CrtPath = val
+		case "plugin_key_path":
+			plugin.KeyPath = val
+		case "plugin_host_header_rewrite":
+			plugin.HostHeaderRewrite = val
+		case "plugin_http_user":
+			plugin.HTTPUser = val
+		case "plugin_http_passwd":
+			plugin.HTTPPassword = val
+		case "plugin_user":
+			plugin.Username = val
+		case "plugin_passwd":
+			plugin.Password = val
+		default:
+			if name, ok := strings.CutPrefix(key, "plugin_header_"); ok {
+				if plugin.RequestHeaders.Set == nil {
+					plugin.RequestHeaders.Set = map[string]string{}
+				}
+				plugin.RequestHeaders.Set[name] = val
+				continue
+			}
+			if name, ok := strings.CutPrefix(key, "header_"); ok {
+				if p.RequestHeaders.Set == nil {
+					p.RequestHeaders.Set = map[string]string{}
+				}
+				p.RequestHeaders.Set[name] = val
+				continue
+			}
+			m, ok := iniProxyExtra[key]
+			if !ok {
+				c.skip(sec.name, key, "没有对应的 TOML 配置项")
+				continue
+			}
+			if p.Extra == nil {
+				p.Extra = map[string]any{}
+			}
+			setKey(p.Extra, m.key, c.value(sec.name, key, val, m.kind))
+		}
+	}
+	if plugin.Type != "" {
+		p.Plugin = plugin
+	}
+	return p
+}
+
+// value 按目标类型转换 INI 中的字符串取值
+func (c *iniConverter) value(section, key, val, kind string) any {
+	switch kind {
+	case "int":
+		return int64(c.int(section, key, val))
+	case "bool":
+		return c.bool(section, key, val)
+	}
+	return val
+}
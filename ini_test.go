@@ -0,0 +1,390 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestConvertINICommon(t *testing.T) {
+	data := []byte(`# 旧版配置
+[common]
+server_addr = frp.example.com
+server_port = 7001
+authentication_method = oidc
+token = secret
+authenticate_heartbeats = true
+authenticate_new_work_conns = true
+oidc_client_id = id
+oidc_client_secret = oidc-secret
+oidc_audience = aud
+oidc_scope = scope
+oidc_token_endpoint_url = https://auth.example.com/token
+oidc_additional_tenant = t1
+protocol = kcp
+connect_server_local_ip = 10.0.0.2
+pool_count = 5
+tcp_mux = false
+heartbeat_interval = 30
+heartbeat_timeout = 90
+tls_enable = true
+tls_cert_file = client.crt
+tls_key_file = client.key
+tls_trusted_ca_file = ca.crt
+tls_server_name = frp.example.com
+user = alice
+login_fail_exit = false
+log_file = ./frpc.log
+log_level = debug
+log_max_days = 3
+admin_addr = 127.0.0.1
+admin_port = 7400
+`)
+	cfg, skipped, err := convertINI(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 0 {
+		t.Errorf("不应有跳过的键: %v", skipped)
+	}
+	want := &ClientConfig{
+		ServerAddr: "frp.example.com",
+		ServerPort: 7001,
+		Auth: AuthConfig{
+			Method:           "oidc",
+			Token:            "secret",
+			AdditionalScopes: []string{"HeartBeats", "NewWorkConns"},
+			OIDC: OIDCConfig{
+				ClientID:                 "id",
+				ClientSecret:             "oidc-secret",
+				Audience:                 "aud",
+				Scope:                    "scope",
+				TokenEndpointURL:         "https://auth.example.com/token",
+				AdditionalEndpointParams: map[string]string{"tenant": "t1"},
+			},
+		},
+		Transport: ClientTransport{
+			Protocol:             "kcp",
+			ConnectServerLocalIP: "10.0.0.2",
+			PoolCount:            5,
+			TCPMux:               boolPtr(false),
+			HeartbeatInterval:    30,
+			HeartbeatTimeout:     90,
+			TLS: TLSClientConfig{
+				Enable:        boolPtr(true),
+				CertFile:      "client.crt",
+				KeyFile:       "client.key",
+				TrustedCaFile: "ca.crt",
+				ServerName:    "frp.example.com",
+			},
+		},
+		Extra: map[string]any{
+			"user":          "alice",
+			"loginFailExit": false,
+			"log":           map[string]any{"to": "./frpc.log", "level": "debug", "maxDays": int64(3)},
+			"webServer":     map[string]any{"addr": "127.0.0.1", "port": int64(7400)},
+		},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("转换结果不一致\n得到 %#v\n期望 %#v", cfg, want)
+	}
+}
+
+func TestConvertINIProxies(t *testing.T) {
+	data := []byte(`[common]
+server_addr = frp.example.com
+
+[ssh]
+type = tcp
+local_ip = 127.0.0.1
+local_port = 22
+remote_port = 6000
+use_encryption = true
+use_compression = true
+bandwidth_limit = 1MB
+bandwidth_limit_mode = server
+group = ssh
+group_key = gk
+health_check_type = tcp
+health_check_timeout_s = 3
+health_check_max_failed = 2
+health_check_interval_s = 10
+proxy_protocol_version = v2
+
+[dns]
+type = udp
+local_port = 53
+remote_port = 6053
+
+[web]
+type = http
+local_port = 80
+custom_domains = a.example.com, b.example.com
+subdomain = www
+locations = /,/api
+host_header_rewrite = internal
+http_user = admin
+http_pwd = pass
+header_X-From-Where = frp
+health_check_type = http
+health_check_url = /status
+
+[secure]
+type = https
+local_port = 443
+custom_domains = s.example.com
+
+[mux]
+type = tcpmux
+multiplexer = httpconnect
+route_by_http_user = user1
+custom_domains = m.example.com
+local_port = 8080
+
+[secret_ssh]
+type = stcp
+sk = key1
+local_port = 22
+
+[p2p]
+type = xtcp
+sk = key2
+local_port = 3389
+
+[secret_dns]
+type = sudp
+sk = key3
+local_port = 53
+`)
+	cfg, skipped, err := convertINI(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 0 {
+		t.Errorf("不应有跳过的键: %v", skipped)
+	}
+	want := []Proxy{
+		{
+			Name: "ssh", Type: "tcp", LocalIP: "127.0.0.1", LocalPort: 22, RemotePort: 6000,
+			Transport:    ProxyTransport{UseEncryption: true, UseCompression: true, BandwidthLimit: "1MB", BandwidthLimitMode: "server"},
+			LoadBalancer: LoadBalancerConfig{Group: "ssh", GroupKey: "gk"},
+			HealthCheck:  HealthCheckConfig{Type: "tcp", TimeoutSeconds: 3, MaxFailed: 2, IntervalSeconds: 10},
+			Extra:        map[string]any{"transport": map[string]any{"proxyProtocolVersion": "v2"}},
+		},
+		{Name: "dns", Type: "udp", LocalPort: 53, RemotePort: 6053},
+		{
+			Name: "web", Type: "http", LocalPort: 80,
+			CustomDomains: []string{"a.example.com", "b.example.com"}, SubDomain: "www", Locations: []string{"/", "/api"},
+			HostHeaderRewrite: "internal", HTTPUser: "admin", HTTPPassword: "pass",
+			RequestHeaders: HeaderOperations{Set: map[string]string{"X-From-Where": "frp"}},
+			HealthCheck:    HealthCheckConfig{Type: "http", Path: "/status"},
+		},
+		{Name: "secure", Type: "https", LocalPort: 443, CustomDomains: []string{"s.example.com"}},
+		{Name: "mux", Type: "tcpmux", Multiplexer: "httpconnect", RouteByHTTPUser: "user1", CustomDomains: []string{"m.example.com"}, LocalPort: 8080},
+		{Name: "secret_ssh", Type: "stcp", SecretKey: "key1", LocalPort: 22},
+		{Name: "p2p", Type: "xtcp", SecretKey: "key2", LocalPort: 3389},
+		{Name: "secret_dns", Type: "sudp", SecretKey: "key3", LocalPort: 53},
+	}
+	if len(cfg.Proxies) != len(want) {
+		t.Fatalf("代理数量 %d，期望 %d", len(cfg.Proxies), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(cfg.Proxies[i], want[i]) {
+			t.Errorf("代理 %s 转换结果不一致\n得到 %#v\n期望 %#v", want[i].Name, cfg.Proxies[i], want[i])
+		}
+	}
+}
+
+func TestConvertINIVisitors(t *testing.T) {
+	data := []byte(`[common]
+server_addr = frp.example.com
+
+[ssh_visitor]
+role = visitor
+type = stcp
+server_name = secret_ssh
+sk = key1
+bind_addr = 127.0.0.1
+bind_port = 6000
+
+[p2p_visitor]
+role = visitor
+type = xtcp
+server_name = p2p
+sk = key2
+bind_port = 6001
+keep_tunnel_open = true
+`)
+	cfg, skipped, err := convertINI(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 0 {
+		t.Errorf("不应有跳过的键: %v", skipped)
+	}
+	want := []Visitor{
+		{Name: "ssh_visitor", Type: "stcp", ServerName: "secret_ssh", SecretKey: "key1", BindAddr: "127.0.0.1", BindPort: 6000},
+		{Name: "p2p_visitor", Type: "xtcp", ServerName: "p2p", SecretKey: "key2", BindPort: 6001, KeepTunnelOpen: true},
+	}
+	if !reflect.DeepEqual(cfg.Visitors, want) {
+		t.Errorf("visitor 转换结果不一致\n得到 %#v\n期望 %#v", cfg.Visitors, want)
+	}
+	if len(cfg.Proxies) != 0 {
+		t.Errorf("visitor 不应转换为代理: %#v", cfg.Proxies)
+	}
+}
+
+func TestConvertINIRange(t *testing.T) {
+	data := []byte(`[common]
+server_addr = frp.example.com
+
+[range:game]
+type = udp
+local_ip = 127.0.0.1
+local_port = 6000-6002,6010
+remote_port = 7000-7003
+
+[range:bad]
+type = tcp
+local_port = 6000-6001
+remote_port = 7000
+`)
+	cfg, skipped, err := convertINI(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Proxy{
+		{Name: "game-6000", Type: "udp", LocalIP: "127.0.0.1", LocalPort: 6000, RemotePort: 7000},
+		{Name: "game-6001", Type: "udp", LocalIP: "127.0.0.1", LocalPort: 6001, RemotePort: 7001},
+		{Name: "game-6002", Type: "udp", LocalIP: "127.0.0.1", LocalPort: 6002, RemotePort: 7002},
+		{Name: "game-6010", Type: "udp", LocalIP: "127.0.0.1", LocalPort: 6010, RemotePort: 7003},
+	}
+	if !reflect.DeepEqual(cfg.Proxies, want) {
+		t.Errorf("端口范围展开结果不一致\n得到 %#v\n期望 %#v", cfg.Proxies, want)
+	}
+	// 端口数量不一致的段整段跳过
+	if len(skipped) != 1 || skipped[0] != "[range:bad] remote_port: 本地端口有 2 个，远程端口有 1 个，数量必须一致" {
+		t.Errorf("跳过的键不一致: %v", skipped)
+	}
+}
+
+func TestConvertINIPlugins(t *testing.T) {
+	data := []byte(`[common]
+server_addr = frp.example.com
+
+[unix]
+type = tcp
+remote_port = 6000
+plugin = unix_domain_socket
+plugin_unix_path = /var/run/docker.sock
+
+[files]
+type = tcp
+remote_port = 6001
+plugin = static_file
+plugin_local_path = /srv/files
+plugin_strip_prefix = static
+plugin_http_user = u
+plugin_http_passwd = p
+
+[to_http]
+type = https
+custom_domains = t.example.com
+plugin = https2http
+plugin_local_addr = 127.0.0.1:80
+plugin_crt_path = ./server.crt
+plugin_key_path = ./server.key
+plugin_host_header_rewrite = 127.0.0.1
+plugin_header_X-From-Where = frp
+
+[socks]
+type = tcp
+remote_port = 6002
+plugin = socks5
+plugin_user = su
+plugin_passwd = sp
+`)
+	cfg, skipped, err := convertINI(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 0 {
+		t.Errorf("不应有跳过的键: %v", skipped)
+	}
+	want := []*ClientPlugin{
+		{Type: "unix_domain_socket", UnixPath: "/var/run/docker.sock"},
+		{Type: "static_file", LocalPath: "/srv/files", StripPrefix: "static", HTTPUser: "u", HTTPPassword: "p"},
+		{
+			Type: "https2http", LocalAddr: "127.0.0.1:80", CrtPath: "./server.crt", KeyPath: "./server.key",
+			HostHeaderRewrite: "127.0.0.1", RequestHeaders: HeaderOperations{Set: map[string]string{"X-From-Where": "frp"}},
+		},
+		{Type: "socks5", Username: "su", Password: "sp"},
+	}
+	for i, p := range cfg.Proxies {
+		if !reflect.DeepEqual(p.Plugin, want[i]) {
+			t.Errorf("代理 %s 的插件不一致\n得到 %#v\n期望 %#v", p.Name, p.Plugin, want[i])
+		}
+		if p.RequestHeaders.Set != nil {
+			t.Errorf("插件请求头不应写入代理 %s: %v", p.Name, p.RequestHeaders.Set)
+		}
+	}
+}
+
+func TestConvertINISkipped(t *testing.T) {
+	data := []byte(`[common]
+server_addr = frp.example.com
+server_port = abc
+tcp_mux = maybe
+meta_owner = ops
+
+[ssh]
+type = tcp
+local_port = 22
+remote_port = 6000
+unknown_key = 1
+use_encryption = yes
+
+[visitor]
+role = visitor
+type = stcp
+bind_port = x
+foo = bar
+`)
+	cfg, skipped, err := convertINI(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"[common] server_port: 不是有效的数字",
+		"[common] tcp_mux: 不是有效的布尔值",
+		"[common] meta_owner: 没有对应的 TOML 配置项",
+		"[ssh] unknown_key: 没有对应的 TOML 配置项",
+		"[ssh] use_encryption: 不是有效的布尔值",
+		"[visitor] bind_port: 不是有效的数字",
+		"[visitor] foo: 没有对应的 TOML 配置项",
+	}
+	if !reflect.DeepEqual(skipped, want) {
+		t.Errorf("跳过的键不一致\n得到 %q\n期望 %q", skipped, want)
+	}
+	if cfg.ServerAddr != "frp.example.com" || len(cfg.Proxies) != 1 || len(cfg.Visitors) != 1 {
+		t.Errorf("其余配置项应正常转换: %#v", cfg)
+	}
+}
+
+func TestConvertINIWithoutCommon(t *testing.T) {
+	if _, _, err := convertINI([]byte("[ssh]\ntype = tcp\n")); err == nil {
+		t.Error("缺少 [common] 段时应报错")
+	}
+}
+
+func TestDecodeImportedDetectsINI(t *testing.T) {
+	data := []byte("[common]\nserver_addr = 1.2.3.4\n\n[ssh]\ntype = tcp\nlocal_port = 22\n")
+	cfg, _, err := decodeImported("frpc", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ServerAddr != "1.2.3.4" || len(cfg.Proxies) != 1 {
+		t.Errorf("没有按 INI 导入: %#v", cfg)
+	}
+}
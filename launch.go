@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// frpcPathFile 保存用户指定的 frpc 路径，留空或不存在时自动查找
+var frpcPathFile = "./frpc_path.txt"
+
+// loadFRPCPath 读取用户指定的 frpc 路径
+func loadFRPCPath() (string, error) {
+	data, err := os.ReadFile(frpcPathFile)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("读取 frpc 路径失败: %v", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// saveFRPCPath 保存用户指定的 frpc 路径，路径为空时恢复自动查找
+func saveFRPCPath(path string) error {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		if err := os.Remove(frpcPathFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("清除 frpc 路径失败: %v", err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(frpcPathFile, []byte(path+"\n"), 0600); err != nil {
+		return fmt.Errorf("保存 frpc 路径失败: %v", err)
+	}
+	return nil
+}
+
+// frpcCandidates 自动查找的候选路径：src 目录、启动器所在目录，按平台的文件名顺序排列
+func frpcCandidates(dir string) []string {
+	dirs := []string{dir}
+	if exe, err := os.Executable(); err == nil {
+		if exeDir := filepath.Dir(exe); exeDir != dir {
+			dirs = append(dirs, exeDir)
+		}
+	}
+	var candidates []string
+	for _, d := range dirs {
+		for _, name := range frpcNames {
+			candidates = append(candidates, filepath.Join(d, name))
+		}
+	}
+	return candidates
+}
+
+// findFRPC 查找 frpc 可执行文件：优先使用用户指定的路径，其次 src 目录和启动器所在目录，最后是 PATH
+func findFRPC(dir string) (string, error) {
+	configured, err := loadFRPCPath()
+	if err != nil {
+		return "", err
+	}
+	if configured != "" {
+		info, err := os.Stat(configured)
+		if err != nil {
+			return "", fmt.Errorf("指定的 frpc 路径不可用: %v", err)
+		}
+		if info.IsDir() {
+			return "", fmt.Errorf("指定的 frpc 路径 %s 是目录", configured)
+		}
+		return filepath.Abs(configured)
+	}
+
+	for _, path := range frpcCandidates(dir) {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	for _, name := range frpcNames {
+		if path, err := exec.LookPath(name); err == nil {
+			return filepath.Abs(path)
+		}
+	}
+	return "", fmt.Errorf("找不到 frpc，请将 %s 放入 %s 目录、启动器所在目录或 PATH 中，或在“frpc 路径”中指定", strings.Join(frpcNames, " 或 "), dir)
+}
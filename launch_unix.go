@@ -0,0 +1,29 @@
+//go:build unix
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// frpcNames Linux 和 macOS 下查找的 frpc 文件名
+var frpcNames = []string{"frpc"}
+
+// configureCommand 让 frpc 运行在独立的进程组中，停止时连同其子进程一起结束
+func configureCommand(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// interruptProcess 向 frpc 所在的进程组发送 SIGTERM，frpc 收到后会通知服务端并关闭代理
+func interruptProcess(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+}
+
+// killProcess 强制结束 frpc 所在的整个进程组
+func killProcess(cmd *exec.Cmd) error {
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+//go:build windows
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"syscall"
+)
+
+// frpcNames Windows 下查找的 frpc 文件名，frpc_auto.exe 兼容旧版本的安装
+var frpcNames = []string{"frpc_auto.exe", "frpc.exe"}
+
+var (
+	kernel32                     = syscall.NewLazyDLL("kernel32.dll")
+	procGenerateConsoleCtrlEvent = kernel32.NewProc("GenerateConsoleCtrlEvent")
+	procAttachConsole            = kernel32.NewProc("AttachConsole")
+	procFreeConsole              = kernel32.NewProc("FreeConsole")
+)
+
+// configureCommand 隐藏 frpc 的控制台窗口，并放入新的进程组以便单独发送 CTRL_BREAK
+func configureCommand(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		HideWindow:    true,
+		CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP,
+	}
+}
+
+// interruptProcess 向 frpc 发送 CTRL_BREAK，frpc 收到后会通知服务端并关闭代理
+func interruptProcess(cmd *exec.Cmd) error {
+	pid := uintptr(cmd.Process.Pid)
+	// 与 frpc 共用控制台时可以直接发送
+	if r, _, _ := procGenerateConsoleCtrlEvent.Call(syscall.CTRL_BREAK_EVENT, pid); r != 0 {
+		return nil
+	}
+	// 启动器没有控制台时，临时附加到 frpc 的控制台再发送
+	if r, _, err := procAttachConsole.Call(pid); r == 0 {
+		return fmt.Errorf("附加 frpc 控制台失败: %v", err)
+	}
+	defer procFreeConsole.Call()
+	if r, _, err := procGenerateConsoleCtrlEvent.Call(syscall.CTRL_BREAK_EVENT, pid); r == 0 {
+		return fmt.Errorf("发送 CTRL_BREAK 失败: %v", err)
+	}
+	return nil
+}
+
+// killProcess 强制结束 frpc 进程
+func killProcess(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
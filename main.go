@@ -1,18 +1,18 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"maps"
 	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
-	"syscall"
-
-	"errors"
+	"sync"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -23,11 +23,20 @@ import (
 )
 
 var (
-	srcDir      = "./src"
-	configFiles []string
-	selectedID  = -1    // 当前选中的配置文件索引
-	isDarkMode  = false // 标记当前是否为黑夜模式
-	frpProcess  *exec.Cmd
+	srcDir       = "./src"
+	templatesDir = "./templates" // 用户模板目录，与 src 同级
+	envDir       = "./envs"      // 各配置的环境变量表，与配置文件分开保存
+	restartDir   = "./restart"   // 各配置的重启策略
+	optionsDir   = "./options"   // 各配置的启动器选项，如加密所有代理
+	configFiles  []string
+	selectedID   = -1    // 当前选中的配置文件索引
+	isDarkMode   = false // 标记当前是否为黑夜模式
+
+	// 片段文件到其主配置的映射，片段不能单独启动
+	fragmentParents map[string]string
+
+	// 各配置的 frpc 进程
+	processes = newSupervisor()
 )
 
 func main() {
@@ -53,6 +62,16 @@ func main() {
 		func() int { return len(configFiles) },
 		func() fyne.CanvasObject { return widget.NewLabel("") },
 		func(i widget.ListItemID, item fyne.CanvasObject) {
+			if _, ok := fragmentParents[configFiles[i]]; ok {
+				// 片段缩进显示在主配置下方
+				item.(*widget.Label).SetText("    └ " + configFiles[i])
+				return
+			}
+			// 非停止状态时在文件名后标出运行状态
+			if state := processes.state(configFiles[i]); state != stateStopped {
+				item.(*widget.Label).SetText(configFiles[i] + "  ● " + state.String())
+				return
+			}
 			item.(*widget.Label).SetText(configFiles[i])
 		},
 	)
@@ -69,12 +88,13 @@ func main() {
 			dialog.ShowError(fmt.Errorf("读取目录失败: %v", err), window)
 			return
 		}
-		configFiles = nil
+		var names []string
 		for _, file := range files {
-			if filepath.Ext(file.Name()) == ".toml" {
-				configFiles = append(configFiles, file.Name())
+			if isConfigFile(file.Name()) {
+				names = append(names, file.Name())
 			}
 		}
+		configFiles, fragmentParents = listProfiles(srcDir, names)
 		configList.Refresh()
 	}
 	refreshConfigFiles()
@@ -88,8 +108,12 @@ func main() {
 	overwriteLogs := true                             // 设置为 true 表示每次启动清空日志
 	// 保留最新 n 行日志
 	maxLogLines := 10
+	// 各 frpc 的输出协程和停止、热重载协程会同时写日志，需要加锁
+	var logMu sync.Mutex
 	// 更新日志显示函数
 	updateLogDisplay := func(entry *widget.Entry, newLine string) {
+		logMu.Lock()
+		defer logMu.Unlock()
 		currentText := entry.Text
 		lines := strings.Split(currentText, "\n")
 		lines = append(lines, newLine)
@@ -100,239 +124,269 @@ func main() {
 		entry.SetText(strings.Join(lines, "\n"))
 	}
 
-	// 添加配置
-	addConfigButton := widget.NewButton("新建配置", func() {
-		serverAddr := widget.NewEntry()
-		serverAddr.SetPlaceHolder("服务器地址")
-		serverPort := widget.NewEntry()
-		serverPort.SetPlaceHolder("服务器端口")
-		authToken := widget.NewEntry()
-		authToken.SetPlaceHolder("鉴权 Token")
+	// 日志文件在启动器运行期间保持打开，所有配置的日志都写入其中
+	var logFile *os.File
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		logs.SetText(fmt.Sprintf("无法创建日志目录: %v", err))
+	} else if overwriteLogs {
+		logFile, err = os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_APPEND, 0644)
+	} else {
+		logFile, err = os.OpenFile(logFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	}
+	if err != nil {
+		logs.SetText(fmt.Sprintf("无法打开日志文件: %v", err))
+	}
+	if logFile != nil {
+		defer logFile.Close()
+	}
 
-		validateIP := func(ip string) bool {
-			return net.ParseIP(ip) != nil
+	// 健康检查状态，从各配置的 frpc 日志中解析
+	healthLabel := widget.NewLabel("")
+	healthLabel.Wrapping = fyne.TextWrapWord
+	healthLabel.Importance = widget.DangerImportance
+
+	// 多个配置同时运行，日志前加上配置名区分
+	processes.onLine = func(name, line string) {
+		line = "[" + name + "] " + line
+		updateLogDisplay(logs, line) // 更新界面上的日志，仅保留最新 10 行
+		if logFile != nil {
+			logFile.WriteString(line + "\n") // 写入日志文件
 		}
-
-		validatePort := func(port string) bool {
-			p, err := strconv.Atoi(port)
-			return err == nil && p >= 0 && p <= 65535
+		if _, ok := parseHealthEvent(line); ok {
+			healthLabel.SetText(processes.healthSummary()) // 更新健康检查状态
 		}
+	}
+	processes.onState = func(name string, state processState, reason string) {
+		if reason != "" {
+			updateLogDisplay(logs, "["+name+"] "+reason) // 退出、重启的原因记入日志
+		}
+		healthLabel.SetText(processes.healthSummary())
+		configList.Refresh()
+	}
 
-		errorLabel := widget.NewLabel("")
-		errorLabel.Hide()
+	// 保存新配置，同名配置已存在时让用户选择另存、覆盖或合并，保存后调用 onSaved，取消时调用 onCancel
+	saveProfile := func(name, format string, cfg *ClientConfig, onSaved func(fileName string), onCancel func()) {
+		save := func(fileName string, cfg *ClientConfig) bool {
+			if err := writeProfile(srcDir, envDir, optionsDir, fileName, cfg); err != nil {
+				dialog.ShowError(err, window)
+				return false
+			}
+			refreshConfigFiles()
+			if onSaved != nil {
+				onSaved(fileName)
+			}
+			return true
+		}
+		fileName := name + "." + format
+		refreshConfigFiles()
+		existing, exists := findProfile(configFiles, name)
+		if !exists {
+			save(fileName, cfg)
+			return
+		}
 
-		markInvalid := func(entry *widget.Entry, valid bool, errorMsg string) {
-			if valid {
-				entry.Validator = nil
-				errorLabel.Hide()
-			} else {
-				entry.Validator = func(s string) error {
-					return errors.New(errorMsg)
+		rename := uniqueProfileName(configFiles, name) + "." + format
+		var dlg *dialog.CustomDialog
+		dlg = dialog.NewCustomWithoutButtons("配置已存在", widget.NewLabel(existing+" 已存在，请选择处理方式"), window)
+		dlg.SetButtons([]fyne.CanvasObject{
+			widget.NewButton("取消", func() {
+				dlg.Hide()
+				if onCancel != nil {
+					onCancel()
 				}
-				errorLabel.SetText(errorMsg)
-				errorLabel.Show()
-			}
-		}
-
-		visitors := []fyne.CanvasObject{}
-		visitorList := container.NewVBox()
-		addVisitorButton := widget.NewButton("添加 Visitor", func() {
-			visitorName := widget.NewEntry()
-			visitorName.SetPlaceHolder("Visitor 名称")
-			visitorType := widget.NewSelect([]string{"xtcp", "stcp"}, func(selected string) {
-				// 可以在此添加选择后的逻辑
-			})
-			visitorType.PlaceHolder = "类型"
-
-			serverName := widget.NewEntry()
-			serverName.SetPlaceHolder("服务器名称")
-			secretKey := widget.NewEntry()
-			secretKey.SetPlaceHolder("密钥")
-			bindAddr := widget.NewEntry()
-			bindAddr.SetPlaceHolder("绑定地址")
-			bindPort := widget.NewEntry()
-			bindPort.SetPlaceHolder("绑定端口")
-			keepTunnelOpen := widget.NewCheck("保持隧道打开", nil)
-
-			visitorForm := container.NewVBox(
-				widget.NewLabel("Visitor 配置项"),
-				visitorName,
-				visitorType,
-				serverName,
-				secretKey,
-				bindAddr,
-				bindPort,
-				keepTunnelOpen,
-			)
-			removeButton := widget.NewButton("移除", func() {
-				visitorList.Remove(visitorForm)
-				for i, v := range visitors {
-					if v == visitorForm {
-						visitors = append(visitors[:i], visitors[i+1:]...)
-						break
+			}),
+			widget.NewButton("另存为 "+rename, func() {
+				dlg.Hide()
+				save(rename, cfg)
+			}),
+			widget.NewButton("覆盖", func() {
+				dlg.Hide()
+				// 只是大小写不同时在 Windows 上是同一个文件，直接写入原文件
+				if strings.EqualFold(existing, fileName) {
+					save(existing, cfg)
+					return
+				}
+				// 先保存新文件，成功后再删除格式不同的旧文件
+				if save(fileName, cfg) {
+					if err := os.Remove(filepath.Join(srcDir, existing)); err != nil {
+						dialog.ShowError(fmt.Errorf("删除旧配置失败: %v", err), window)
 					}
+					refreshConfigFiles()
+				}
+			}),
+			widget.NewButton("合并", func() {
+				dlg.Hide()
+				old, err := readProfile(srcDir, envDir, optionsDir, existing)
+				if err != nil {
+					dialog.ShowError(err, window)
+					return
 				}
-			})
-			visitorForm.Add(removeButton)
-			visitors = append(visitors, visitorForm)
-			visitorList.Add(visitorForm)
+				save(existing, mergeConfigs(old, cfg))
+			}),
 		})
+		dlg.Show()
+	}
 
-		proxies := []fyne.CanvasObject{}
-		proxyList := container.NewVBox()
-		addProxyButton := widget.NewButton("添加 Proxy", func() {
-			proxyName := widget.NewEntry()
-			proxyName.SetPlaceHolder("Proxy 名称")
-			proxyType := widget.NewSelect([]string{"tcp", "udp", "xtcp", "stcp"}, func(selected string) {
-				// 可以在此添加选择后的逻辑
-			})
-			proxyType.PlaceHolder = "类型"
-
-			localAddr := widget.NewEntry()
-			localAddr.SetPlaceHolder("本地地址")
-			localAddr.OnChanged = func(text string) {
-				markInvalid(localAddr, validateIP(text), "无效的本地地址")
-			}
+	// 添加配置，名称冲突时取消会回到表单，已填写的内容不会丢失
+	var showCreateForm func(name string, cfg *ClientConfig, required []string)
+	showCreateForm = func(name string, cfg *ClientConfig, required []string) {
+		showNewProfileForm(window, name, cfg, required, func(name string, cfg *ClientConfig) error {
+			saveProfile(name, formatTOML, cfg, nil, func() { showCreateForm(name, cfg, required) })
+			return nil
+		})
+	}
+	addConfigButton := widget.NewButton("新建配置", func() {
+		showCreateForm("", &ClientConfig{}, nil)
+	})
 
-			localPort := widget.NewEntry()
-			localPort.SetPlaceHolder("本地端口")
-			localPort.OnChanged = func(text string) {
-				markInvalid(localPort, validatePort(text), "端口范围应为 0-65535")
+	// 从内置预设或用户模板新建配置
+	templateButton := widget.NewButton("从模板新建", func() {
+		templates, err := listTemplates(templatesDir)
+		if err != nil {
+			dialog.ShowError(err, window)
+		}
+		names := make([]string, len(templates))
+		for i, t := range templates {
+			names[i] = t.name
+			if t.path != "" {
+				names[i] += " (自定义)"
 			}
-
-			remotePort := widget.NewEntry()
-			remotePort.SetPlaceHolder("远程端口")
-			remotePort.OnChanged = func(text string) {
-				markInvalid(remotePort, validatePort(text), "端口范围应为 0-65535")
+		}
+		templateSelect := widget.NewSelect(names, nil)
+		templateSelect.SetSelectedIndex(0)
+		content := container.NewVBox(widget.NewLabel("选择模板"), templateSelect)
+		dialog.ShowCustomConfirm("从模板新建", "使用", "取消", content, func(confirm bool) {
+			if !confirm || templateSelect.SelectedIndex() < 0 {
+				return
 			}
-
-			secretKey := widget.NewEntry()
-			secretKey.SetPlaceHolder("密钥")
-
-			proxyType.OnChanged = func(selected string) {
-				if selected == "xtcp" {
-					remotePort.Hide()
-					secretKey.Show()
-				} else {
-					remotePort.Show()
-					secretKey.Hide()
-				}
+			cfg, required, err := loadTemplate(templates[templateSelect.SelectedIndex()])
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
 			}
+			showCreateForm("", cfg, required)
+		}, window)
+	})
 
-			proxyForm := container.NewVBox(
-				widget.NewLabel("Proxy 配置项"),
-				proxyName,
-				proxyType,
-				localAddr,
-				localPort,
-				remotePort,
-				secretKey,
-			)
-			removeButton := widget.NewButton("移除", func() {
-				proxyList.Remove(proxyForm)
-				for i, p := range proxies {
-					if p == proxyForm {
-						proxies = append(proxies[:i], proxies[i+1:]...)
-						break
-					}
-				}
-			})
-			proxyForm.Add(removeButton)
-			proxies = append(proxies, proxyForm)
-			proxyList.Add(proxyForm)
-		})
-
-		// 使用 container.NewVScroll 来实现滚动效果
-		form := dialog.NewCustomConfirm("新建配置", "保存", "取消", container.NewVScroll(
-			container.NewVBox(
-				widget.NewLabel("服务器配置项"),
-				serverAddr,
-				serverPort,
-				authToken,
-				widget.NewLabel("Visitors"),
-				visitorList,
-				addVisitorButton,
-				widget.NewLabel("Proxies"),
-				proxyList,
-				addProxyButton,
-				errorLabel,
-			),
-		), func(confirm bool) {
+	// 把选中的配置保存为用户模板，服务器地址和密钥会替换为占位符
+	saveAsTemplate := func(fileName string) {
+		nameEntry := widget.NewEntry()
+		nameEntry.SetPlaceHolder("模板名称")
+		nameEntry.SetText(profileName(fileName))
+		content := container.NewVBox(widget.NewLabel("模板名称"), nameEntry)
+		dialog.ShowCustomConfirm("保存为模板", "保存", "取消", content, func(confirm bool) {
 			if !confirm {
 				return
 			}
-			if !validateIP(serverAddr.Text) {
-				markInvalid(serverAddr, false, "无效的服务器地址")
+			cfg, err := loadConfig(filepath.Join(srcDir, fileName))
+			if err != nil {
+				dialog.ShowError(err, window)
 				return
 			}
-			if !validatePort(serverPort.Text) {
-				markInvalid(serverPort, false, "端口范围应为 0-65535")
+			dstName := templateFileName(nameEntry.Text)
+			dstPath := filepath.Join(templatesDir, dstName)
+			save := func() {
+				if err := saveTemplate(dstPath, cfg); err != nil {
+					dialog.ShowError(err, window)
+					return
+				}
+				dialog.ShowInformation("成功", "已保存为模板 "+profileName(dstName), window)
+			}
+			if _, err := os.Stat(dstPath); err == nil {
+				dialog.ShowConfirm("模板已存在", profileName(dstName)+" 已存在，是否覆盖？", func(overwrite bool) {
+					if overwrite {
+						save()
+					}
+				}, window)
 				return
 			}
+			save()
+		}, window)
+	}
 
-			// 将 IP 地址中的中间部分数字替换为 *
-			maskedAddr := maskIP(serverAddr.Text)
-			// 保存配置文件
-			fileName := "config_" + maskedAddr + ".toml"
-			content := fmt.Sprintf(`serverAddr = "%s"
-serverPort = %s
-auth.method = "token"
-auth.token = "%s"
-
-`, serverAddr.Text, serverPort.Text, authToken.Text)
-			for _, visitor := range visitors {
-				visitorForm := visitor.(*fyne.Container).Objects
-				content += fmt.Sprintf(`[[visitors]]
-name = "%s"
-type = "%s"
-serverName = "%s"
-secretKey = "%s"
-bindAddr = "%s"
-bindPort = %s
-keepTunnelOpen = %t
-
-`,
-					visitorForm[1].(*widget.Entry).Text,
-					visitorForm[2].(*widget.Select).Selected,
-					visitorForm[3].(*widget.Entry).Text,
-					visitorForm[4].(*widget.Entry).Text,
-					visitorForm[5].(*widget.Entry).Text,
-					visitorForm[6].(*widget.Entry).Text,
-					visitorForm[7].(*widget.Check).Checked,
-				)
-			}
-			for _, proxy := range proxies {
-				proxyForm := proxy.(*fyne.Container).Objects
-				content += fmt.Sprintf(`[[proxies]]
-name = "%s"
-type = "%s"
-localIP = "%s"
-localPort = %s
-`,
-					proxyForm[1].(*widget.Entry).Text,
-					proxyForm[2].(*widget.Select).Selected,
-					proxyForm[3].(*widget.Entry).Text,
-					proxyForm[4].(*widget.Entry).Text,
-				)
-				if proxyForm[2].(*widget.Select).Selected != "xtcp" {
-					content += fmt.Sprintf("remotePort = %s\n", proxyForm[5].(*widget.Entry).Text)
-				}
-				if proxyForm[2].(*widget.Select).Selected == "xtcp" {
-					content += fmt.Sprintf("secretKey = \"%s\"\n", proxyForm[6].(*widget.Entry).Text)
+	// 设置配置的重启策略，运行中的配置在下次退出时生效
+	editRestartPolicy := func(fileName string) {
+		policy, err := loadRestartPolicy(restartDir, fileName)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		modeSelect := widget.NewSelect(restartModes, nil)
+		modeSelect.SetSelected(policy.Mode)
+		intEntry := func(n int) *widget.Entry {
+			entry := widget.NewEntry()
+			entry.SetText(strconv.Itoa(n))
+			return entry
+		}
+		maxRetries := intEntry(policy.MaxRetries)
+		initialBackoff := intEntry(policy.InitialBackoffSeconds)
+		maxBackoff := intEntry(policy.MaxBackoffSeconds)
+		stable := intEntry(policy.StableSeconds)
+		stopTimeout := intEntry(policy.StopTimeoutSeconds)
+		content := container.NewVBox(
+			widget.NewLabel("重启方式（never 不重启，on-failure 异常退出时重启，always 除手动停止外总是重启）"),
+			modeSelect,
+			widget.NewLabel("最多连续重启次数（0 表示不限）"),
+			maxRetries,
+			widget.NewLabel("首次重启间隔（秒），之后每次翻倍"),
+			initialBackoff,
+			widget.NewLabel("最大重启间隔（秒）"),
+			maxBackoff,
+			widget.NewLabel("连续运行超过该秒数后重新计算重启次数（0 表示不重新计算）"),
+			stable,
+			widget.NewLabel("停止时等待 frpc 正常退出的秒数，超时后强制结束"),
+			stopTimeout,
+		)
+		dialog.ShowCustomConfirm("重启策略", "保存", "取消", content, func(confirm bool) {
+			if !confirm {
+				return
+			}
+			fields := []struct {
+				entry *widget.Entry
+				value *int
+			}{
+				{maxRetries, &policy.MaxRetries},
+				{initialBackoff, &policy.InitialBackoffSeconds},
+				{maxBackoff, &policy.MaxBackoffSeconds},
+				{stable, &policy.StableSeconds},
+				{stopTimeout, &policy.StopTimeoutSeconds},
+			}
+			for _, field := range fields {
+				n, err := strconv.Atoi(strings.TrimSpace(field.entry.Text))
+				if err != nil {
+					dialog.ShowError(fmt.Errorf("请输入整数: %q", field.entry.Text), window)
+					return
 				}
-				content += "\n"
+				*field.value = n
 			}
+			policy.Mode = modeSelect.Selected
+			if err := saveRestartPolicy(restartDir, fileName, &policy); err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			processes.setPolicy(fileName, policy)
+		}, window)
+	}
 
-			err := os.WriteFile(filepath.Join(srcDir, fileName), []byte(content), 0600)
-			if err != nil {
-				dialog.ShowError(fmt.Errorf("保存配置文件失败: %v", err), window)
+	// 导入配置前询问配置名称
+	importProfile := func(name, format string, cfg *ClientConfig, message string, skipped []string) {
+		nameEntry := widget.NewEntry()
+		nameEntry.SetPlaceHolder("配置名称，留空则按服务器地址生成")
+		nameEntry.SetText(name)
+		content := container.NewVBox(widget.NewLabel("配置名称"), nameEntry)
+		dialog.ShowCustomConfirm("导入配置", "保存", "取消", content, func(confirm bool) {
+			if !confirm {
 				return
 			}
-			refreshConfigFiles()
+			name := sanitizeProfileName(nameEntry.Text)
+			if name == "" {
+				name = defaultProfileName(cfg)
+			}
+			saveProfile(name, format, cfg, func(fileName string) {
+				dialog.ShowInformation("成功", importMessage(message+"，已保存为 "+fileName, skipped), window)
+			}, nil)
 		}, window)
-		form.Resize(fyne.NewSize(700, 500)) // 调整添加配置窗口的尺寸
-		form.Show()
-	})
+	}
 
 	// 切换主题的按钮
 	switchThemeButton := widget.NewButton("切换主题", func() {
@@ -343,32 +397,93 @@ localPort = %s
 		}
 		isDarkMode = !isDarkMode
 	})
-	// 修改配置
-	modifyConfig := func(fileName string) {
+	// 保存后让运行中的 frpc 使用新配置，在启动和停止 FRP 之后定义
+	var reloadProfile func(fileName string)
+
+	// 配置的选项，片段使用主配置的选项
+	optionsOf := func(fileName string) (profileOptions, error) {
+		if parent, ok := fragmentParents[fileName]; ok {
+			fileName = parent
+		}
+		return loadProfileOptions(optionsDir, fileName)
+	}
+
+	// 编辑配置原文，直接写回文本以保留注释和环境变量占位符
+	editRaw := func(fileName string) {
 		filePath := filepath.Join(srcDir, fileName)
 		content, err := os.ReadFile(filePath)
 		if err != nil {
 			dialog.ShowError(fmt.Errorf("读取配置文件失败: %v", err), window)
 			return
 		}
-		entry := widget.NewMultiLineEntry()
-		entry.SetText(string(content))
-		dlg := dialog.NewCustomConfirm("修改配置", "保存", "取消", entry, func(confirm bool) {
-			if confirm {
-				err := os.WriteFile(filePath, []byte(entry.Text), 0600)
-				if err != nil {
-					dialog.ShowError(fmt.Errorf("保存配置文件失败: %v", err), window)
+		opts, err := optionsOf(fileName)
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		showRawEditor(window, "编辑原文", configFormat(fileName), string(content), func(text string) error {
+			if err := opts.checkRaw(configFormat(fileName), text); err != nil {
+				return err
+			}
+			if err := os.WriteFile(filePath, []byte(text), 0600); err != nil {
+				return fmt.Errorf("保存配置文件失败: %v", err)
+			}
+			refreshConfigFiles()
+			reloadProfile(fileName)
+			return nil
+		})
+	}
+	// 修改配置
+	modifyConfig := func(fileName string) {
+		filePath := filepath.Join(srcDir, fileName)
+		if parent, ok := fragmentParents[fileName]; ok {
+			if cfg, err := loadConfig(filePath); err == nil {
+				if cfg.Options, err = optionsOf(fileName); err != nil {
+					dialog.ShowError(err, window)
 					return
 				}
-				refreshConfigFiles()
+				// 片段与主配置及其他片段合并后校验
+				validate := func(fragment *ClientConfig) ValidationErrors {
+					parentCfg, err := readProfile(srcDir, envDir, optionsDir, parent)
+					if err != nil {
+						return ValidationErrors{{Field: parent, Msg: err.Error()}}
+					}
+					return validateMerged(srcDir, parent, parentCfg, fileName, map[string]*ClientConfig{fileName: fragment})
+				}
+				showFragmentForm(window, "修改片段", cfg, validate, func(cfg *ClientConfig) error {
+					if err := saveConfig(filePath, cfg); err != nil {
+						return err
+					}
+					refreshConfigFiles()
+					reloadProfile(fileName)
+					return nil
+				})
+				return
 			}
-		}, window)
-		dlg.Resize(fyne.NewSize(700, 500)) // 调整修改配置窗口的尺寸
-		dlg.Show()
+		}
+		cfg, err := readProfile(srcDir, envDir, optionsDir, fileName)
+		if err != nil {
+			// 无法解析为表单时退回原始编辑器
+			editRaw(fileName)
+			return
+		}
+		showConfigForm(window, "修改配置", cfg, func(cfg *ClientConfig) error {
+			err := writeProfile(srcDir, envDir, optionsDir, fileName, cfg)
+			if err != nil {
+				return err
+			}
+			refreshConfigFiles()
+			reloadProfile(fileName)
+			return nil
+		})
 	}
 
 	// 删除配置
 	deleteConfig := func(fileName string) {
+		if processes.state(fileName).active() {
+			dialog.ShowInformation("提示", fileName+" 正在运行，请先停止", window)
+			return
+		}
 		dlg := dialog.NewConfirm("删除配置", "确定要删除该配置文件吗？", func(confirm bool) {
 			if confirm {
 				err := os.Remove(filepath.Join(srcDir, fileName))
@@ -377,101 +492,264 @@ localPort = %s
 					return
 				}
 				refreshConfigFiles()
+				// 没有其他格式的同名配置时一并删除环境变量
+				if _, exists := findProfile(configFiles, profileName(fileName)); !exists {
+					if err := saveEnv(envDir, fileName, nil); err != nil {
+						dialog.ShowError(err, window)
+					}
+					if err := saveRestartPolicy(restartDir, fileName, nil); err != nil {
+						dialog.ShowError(err, window)
+					}
+					if err := saveProfileOptions(optionsDir, fileName, profileOptions{}); err != nil {
+						dialog.ShowError(err, window)
+					}
+				}
+			}
+		}, window)
+		dlg.Show()
+	}
+
+	// 转换配置格式，原文件保留
+	convertConfig := func(fileName string) {
+		formatSelect := widget.NewSelect(configFormats, nil)
+		formatSelect.SetSelected(configFormat(fileName))
+		content := container.NewVBox(widget.NewLabel("转换为"), formatSelect)
+		dialog.ShowCustomConfirm("转换格式", "转换", "取消", content, func(confirm bool) {
+			if !confirm || formatSelect.Selected == configFormat(fileName) {
+				return
+			}
+			cfg, err := loadConfig(filepath.Join(srcDir, fileName))
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			opts, err := optionsOf(fileName)
+			if err != nil {
+				dialog.ShowError(err, window)
+				return
+			}
+			opts.apply(cfg)
+			dstName := strings.TrimSuffix(fileName, filepath.Ext(fileName)) + "." + formatSelect.Selected
+			dstPath := filepath.Join(srcDir, dstName)
+			convert := func() {
+				err := saveConfig(dstPath, cfg)
+				if err != nil {
+					dialog.ShowError(err, window)
+					return
+				}
+				refreshConfigFiles()
+				dialog.ShowInformation("成功", "已转换为 "+dstName, window)
 			}
+			if _, err := os.Stat(dstPath); err == nil {
+				dialog.ShowConfirm("文件已存在", dstName+" 已存在，是否覆盖？", func(overwrite bool) {
+					if overwrite {
+						convert()
+					}
+				}, window)
+				return
+			}
+			convert()
 		}, window)
+	}
+
+	// 负载均衡分组视图，汇总所有配置文件中的分组并检查是否一致
+	showGroups := func() {
+		groups, errs := collectGroups(srcDir, configFiles)
+		content := container.NewVBox()
+		if len(groups) == 0 {
+			content.Add(widget.NewLabel("没有配置负载均衡分组的代理"))
+		}
+		for _, g := range groups {
+			content.Add(widget.NewLabelWithStyle(fmt.Sprintf("分组 %s (%d 个代理)", g.name, len(g.members)), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+			for _, m := range g.members {
+				line := fmt.Sprintf("  %s: %s [%s]", m.file, m.proxy.Name, m.proxy.Type)
+				if m.proxy.Type == "tcp" {
+					line += fmt.Sprintf(" 远程端口 %d", m.proxy.RemotePort)
+				}
+				content.Add(widget.NewLabel(line))
+			}
+			for _, problem := range g.problems() {
+				label := widget.NewLabel("  " + problem)
+				label.Importance = widget.DangerImportance
+				content.Add(label)
+			}
+		}
+		for _, err := range errs {
+			label := widget.NewLabel(fmt.Sprintf("无法读取 %v", err))
+			label.Importance = widget.WarningImportance
+			content.Add(label)
+		}
+		dlg := dialog.NewCustom("负载均衡分组", "关闭", container.NewVScroll(content), window)
+		dlg.Resize(fyne.NewSize(600, 400))
 		dlg.Show()
 	}
 
 	// 启动和停止 FRP
-	startFRP := func() {
-		if selectedID < 0 || selectedID >= len(configFiles) {
-			dialog.ShowInformation("提示", "请先选择一个配置文件", window)
-			return
+	startProfile := func(fileName string) error {
+		if parent, ok := fragmentParents[fileName]; ok {
+			return fmt.Errorf("%s 是 %s 的 includes 片段，不能单独启动，请启动 %s", fileName, parent, parent)
+		}
+
+		// 启动前校验配置，避免 frpc 启动后才报错退出
+		envs, err := loadEnv(envDir, fileName)
+		if err != nil {
+			return err
+		}
+		cfg, missing, err := resolveConfig(filepath.Join(srcDir, fileName), envs)
+		if len(missing) > 0 {
+			return missingEnvError(missing)
+		}
+		if err != nil {
+			return err
+		}
+		if errs := validateMerged(srcDir, fileName, cfg, fileName, nil); len(errs) > 0 {
+			return fmt.Errorf("%s 配置校验失败:\n%v", fileName, errs)
 		}
 
 		// 获取当前工作目录
 		dir, err := os.Getwd()
 		if err != nil {
-			logs.SetText(fmt.Sprintf("获取当前目录失败: %v", err))
-			return
+			return fmt.Errorf("获取当前目录失败: %v", err)
 		}
 
-		// 构建frpc.exe的完整路径
-		configPath := filepath.Join(dir, "src", configFiles[selectedID])
-
-		// 执行frpc.exe，传递配置文件路径
-		cmd := exec.Command(filepath.Join(dir, "src", "frpc_auto.exe"), "-c", configPath)
-		stdout, _ := cmd.StdoutPipe()
-		stderr, _ := cmd.StderrPipe()
-
-		// 打开日志文件
-		var logFile *os.File
-		if overwriteLogs {
-			logFile, err = os.OpenFile(logFilePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-		} else {
-			logFile, err = os.OpenFile(logFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		}
+		// 查找 frpc 可执行文件
+		frpcPath, err := findFRPC(filepath.Join(dir, srcDir))
 		if err != nil {
-			logs.SetText(fmt.Sprintf("无法打开日志文件: %v", err))
-			return
+			return err
 		}
-		defer logFile.Close()
 
-		// 日志协程
-		go func() {
-			scanner := bufio.NewScanner(stdout)
-			for scanner.Scan() {
-				line := scanner.Text()
-				updateLogDisplay(logs, line)     // 更新界面上的日志，仅保留最新 10 行
-				logFile.WriteString(line + "\n") // 写入日志文件
-			}
-		}()
-		go func() {
-			scanner := bufio.NewScanner(stderr)
-			for scanner.Scan() {
-				line := scanner.Text()
-				updateLogDisplay(logs, line)     // 更新界面上的日志，仅保留最新 10 行
-				logFile.WriteString(line + "\n") // 写入日志文件
-			}
-		}()
-		cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true} // 隐藏控制台窗口
+		// 构建配置文件的完整路径
+		configPath := filepath.Join(dir, srcDir, fileName)
 
-		err = cmd.Start()
+		policy, err := loadRestartPolicy(restartDir, fileName)
 		if err != nil {
-			logs.SetText(fmt.Sprintf("启动 FRP 失败: %v", err))
-			return
+			return err
 		}
 
-		frpProcess = cmd // 保存进程对象
+		// 执行 frpc，传递配置文件路径，重启时重新构建命令
+		launch := func() *exec.Cmd {
+			cmd := exec.Command(frpcPath, "-c", configPath)
+			cmd.Dir = filepath.Join(dir, srcDir)             // 配置中的相对路径相对配置所在目录解析
+			cmd.Env = append(os.Environ(), envList(envs)...) // 注入配置的环境变量
+			configureCommand(cmd)                            // 按平台设置进程属性
+			return cmd
+		}
 
-		go func() {
-			err = cmd.Wait()
-			if err != nil {
-				logs.SetText(fmt.Sprintf("FRP 运行中断: %v", err))
-			} else {
-				logs.SetText(logs.Text + "\nFRP 已成功运行")
-			}
-		}()
+		admin, _ := adminAPIOf(cfg)
+		if err := processes.start(fileName, launch, policy, launchInfo{admin: admin, envs: envs}); err != nil {
+			return err
+		}
+		updateLogDisplay(logs, "["+fileName+"] FRP 已启动，连接服务器 "+net.JoinHostPort(cfg.ServerAddr, strconv.Itoa(cfg.serverPort())))
+		return nil
+	}
 
-		logs.SetText(logs.Text + "\nFRP 已启动...")
+	startFRP := func() {
+		if selectedID < 0 || selectedID >= len(configFiles) {
+			dialog.ShowInformation("提示", "请先选择一个配置文件", window)
+			return
+		}
+		if err := startProfile(configFiles[selectedID]); err != nil {
+			dialog.ShowError(err, window)
+		}
 	}
 
 	stopFRP := func() {
-		if frpProcess == nil {
-			logs.SetText(logs.Text + "\n没有运行中的 FRP 进程")
+		if selectedID < 0 || selectedID >= len(configFiles) {
+			dialog.ShowInformation("提示", "请先选择一个配置文件", window)
 			return
 		}
+		if err := processes.stop(configFiles[selectedID]); err != nil {
+			updateLogDisplay(logs, err.Error())
+		}
+	}
 
-		// 强制杀死进程
-		err := frpProcess.Process.Kill()
+	reloadProfile = func(fileName string) {
+		// 片段由主配置的 frpc 加载
+		if parent, ok := fragmentParents[fileName]; ok {
+			fileName = parent
+		}
+		launched, ok := processes.launchInfo(fileName)
+		if !ok {
+			return
+		}
+		restart := func(reason string) {
+			dialog.ShowConfirm("重启 FRP", reason+"，是否重启 "+fileName+" 使新配置生效？重启期间隧道会中断", func(confirm bool) {
+				if !confirm {
+					return
+				}
+				go func() {
+					done, err := processes.stopProcess(fileName)
+					if err != nil {
+						updateLogDisplay(logs, err.Error())
+						return
+					}
+					<-done
+					if err := startProfile(fileName); err != nil {
+						updateLogDisplay(logs, "["+fileName+"] 重启 FRP 失败: "+err.Error())
+					}
+				}()
+			}, window)
+		}
+		// frpc 热重载时用自身启动时的环境变量渲染配置，环境变量改了只能重启
+		envs, err := loadEnv(envDir, fileName)
 		if err != nil {
-			logs.SetText(fmt.Sprintf("强制停止 FRP 失败: %v", err))
-		} else {
-			logs.SetText(logs.Text + "\nFRP 已停止")
+			dialog.ShowError(err, window)
+			return
+		}
+		if !maps.Equal(envs, launched.envs) {
+			restart("环境变量已修改，热重载不会使用新的环境变量")
+			return
+		}
+		if launched.admin == nil {
+			restart("配置中没有 webServer 管理接口，无法热重载")
+			return
+		}
+		go func() {
+			err := reloadFRPC(context.Background(), reloadClient, launched.admin)
+			var rejected *reloadError
+			switch {
+			case err == nil:
+				updateLogDisplay(logs, "["+fileName+"] 配置已热重载")
+			case errors.As(err, &rejected):
+				updateLogDisplay(logs, "["+fileName+"] 热重载失败: "+err.Error())
+				dialog.ShowError(err, window)
+			case errors.Is(err, errReloadUnavailable):
+				updateLogDisplay(logs, "["+fileName+"] "+err.Error())
+				restart(err.Error())
+			default:
+				updateLogDisplay(logs, "["+fileName+"] "+err.Error())
+				dialog.ShowError(err, window)
+			}
+		}()
+	}
+
+	// 启动所有未运行的配置，片段跳过
+	startAll := func() {
+		var errs []error
+		for _, fileName := range configFiles {
+			if _, ok := fragmentParents[fileName]; ok || processes.state(fileName).active() {
+				continue
+			}
+			if err := startProfile(fileName); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if len(errs) > 0 {
+			dialog.ShowError(errors.Join(errs...), window)
 		}
+	}
 
-		// 清理进程
-		frpProcess = nil
+	stopAll := func() {
+		if len(processes.running()) == 0 {
+			updateLogDisplay(logs, "没有运行中的 FRP 进程")
+			return
+		}
+		// 等待所有进程退出可能需要几秒，不阻塞界面
+		go func() {
+			if err := processes.stopAll(); err != nil {
+				updateLogDisplay(logs, err.Error())
+			}
+		}()
 	}
 
 	// 配置操作按钮
@@ -483,6 +761,13 @@ localPort = %s
 				dialog.ShowInformation("提示", "请先选择一个配置文件", window)
 			}
 		}),
+		widget.NewButton("编辑原文", func() {
+			if selectedID >= 0 && selectedID < len(configFiles) {
+				editRaw(configFiles[selectedID])
+			} else {
+				dialog.ShowInformation("提示", "请先选择一个配置文件", window)
+			}
+		}),
 		widget.NewButton("删除配置", func() {
 			if selectedID >= 0 && selectedID < len(configFiles) {
 				deleteConfig(configFiles[selectedID])
@@ -490,6 +775,27 @@ localPort = %s
 				dialog.ShowInformation("提示", "请先选择一个配置文件", window)
 			}
 		}),
+		widget.NewButton("重启策略", func() {
+			if selectedID >= 0 && selectedID < len(configFiles) {
+				editRestartPolicy(configFiles[selectedID])
+			} else {
+				dialog.ShowInformation("提示", "请先选择一个配置文件", window)
+			}
+		}),
+		widget.NewButton("保存为模板", func() {
+			if selectedID >= 0 && selectedID < len(configFiles) {
+				saveAsTemplate(configFiles[selectedID])
+			} else {
+				dialog.ShowInformation("提示", "请先选择一个配置文件", window)
+			}
+		}),
+		widget.NewButton("转换格式", func() {
+			if selectedID >= 0 && selectedID < len(configFiles) {
+				convertConfig(configFiles[selectedID])
+			} else {
+				dialog.ShowInformation("提示", "请先选择一个配置文件", window)
+			}
+		}),
 	)
 	// 导入配置按钮
 	importConfigButton := widget.NewButton("导入配置", func() {
@@ -510,23 +816,27 @@ localPort = %s
 					}
 
 					// 确保src目录存在
-					if _, err := os.Stat("src"); os.IsNotExist(err) {
-						err := os.Mkdir("src", 0755)
+					if _, err := os.Stat(srcDir); os.IsNotExist(err) {
+						err := os.Mkdir(srcDir, 0755)
 						if err != nil {
 							dialog.ShowError(fmt.Errorf("创建src目录失败: %v", err), window)
 							return
 						}
 					}
 
-					// 保存文件到src目录
-					dstPath := filepath.Join("src", filepath.Base(uc.URI().Path()))
-					err = os.WriteFile(dstPath, content, 0600)
+					// 保存文件到src目录，YAML/JSON 保持原格式，旧版 INI 配置转换为 TOML
+					cfg, skipped, err := decodeImported(uc.URI().Path(), content)
 					if err != nil {
-						dialog.ShowError(fmt.Errorf("保存文件失败: %v", err), window)
+						dialog.ShowError(err, window)
 						return
 					}
-					refreshConfigFiles()
-					dialog.ShowInformation("成功", "配置文件导入成功", window)
+					baseName := filepath.Base(uc.URI().Path())
+					format := configFormat(baseName)
+					if format == "" {
+						format = formatTOML
+					}
+					name := sanitizeProfileName(strings.TrimSuffix(baseName, filepath.Ext(baseName)))
+					importProfile(name, format, cfg, "配置文件导入成功", skipped)
 				}
 			}, window)
 			openFileDialog.Show()
@@ -558,8 +868,8 @@ localPort = %s
 			}
 
 			// 确保src目录存在
-			if _, err := os.Stat("src"); os.IsNotExist(err) {
-				err := os.Mkdir("src", 0755)
+			if _, err := os.Stat(srcDir); os.IsNotExist(err) {
+				err := os.Mkdir(srcDir, 0755)
 				if err != nil {
 					dialog.ShowError(fmt.Errorf("创建src目录失败: %v", err), window)
 					return
@@ -567,14 +877,12 @@ localPort = %s
 			}
 
 			// 生成文件路径并保存解码后的文件
-			dstPath := filepath.Join("src", "config.toml") // 默认保存为config.toml
-			err = os.WriteFile(dstPath, decodedData, 0600)
+			cfg, skipped, err := decodeImported("", decodedData)
 			if err != nil {
-				dialog.ShowError(fmt.Errorf("保存解码文件失败: %v", err), window)
+				dialog.ShowError(err, window)
 				return
 			}
-			refreshConfigFiles()
-			dialog.ShowInformation("成功", "Base64配置导入成功", window)
+			importProfile(defaultProfileName(cfg), formatTOML, cfg, "Base64配置导入成功", skipped)
 		})
 
 		// 显示导入配置对话框
@@ -640,40 +948,92 @@ localPort = %s
 		}
 	})
 
+	// 指定 frpc 路径，留空时自动查找
+	frpcPathButton := widget.NewButton("frpc 路径", func() {
+		configured, err := loadFRPCPath()
+		if err != nil {
+			dialog.ShowError(err, window)
+			return
+		}
+		pathEntry := widget.NewEntry()
+		pathEntry.SetText(configured)
+		pathEntry.SetPlaceHolder("留空自动查找 src 目录、启动器所在目录和 PATH")
+		browseButton := widget.NewButton("浏览", func() {
+			dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+				if err != nil || reader == nil {
+					return
+				}
+				defer reader.Close()
+				pathEntry.SetText(reader.URI().Path())
+			}, window)
+		})
+		detected := widget.NewLabel("")
+		if dir, err := os.Getwd(); err == nil {
+			if path, err := findFRPC(filepath.Join(dir, srcDir)); err == nil {
+				detected.SetText("当前使用: " + path)
+			} else {
+				detected.SetText(err.Error())
+			}
+		}
+		detected.Wrapping = fyne.TextWrapWord
+		content := container.NewVBox(container.NewBorder(nil, nil, nil, browseButton, pathEntry), detected)
+		dlg := dialog.NewCustomConfirm("frpc 路径", "保存", "取消", content, func(confirm bool) {
+			if !confirm {
+				return
+			}
+			if err := saveFRPCPath(pathEntry.Text); err != nil {
+				dialog.ShowError(err, window)
+			}
+		}, window)
+		dlg.Resize(fyne.NewSize(600, 200))
+		dlg.Show()
+	})
+
 	// 布局
 	leftPanel := container.NewVBox(
 		addConfigButton,
+		templateButton,
 		importConfigButton,
 		exportConfigButton,
 		configActions,
+		widget.NewButton("负载均衡分组", showGroups),
 		widget.NewButton("启动 FRP", startFRP),
 		widget.NewButton("停止 FRP", stopFRP),
+		widget.NewButton("全部启动", startAll),
+		widget.NewButton("全部停止", stopAll),
+		frpcPathButton,
 		switchThemeButton,
 		widget.NewLabel("  powered by Deepsea"),
 	)
-	listAndLogs := container.NewVSplit(configList, logs)
+	listAndLogs := container.NewVSplit(configList, container.NewBorder(nil, healthLabel, nil, nil, logs))
 	listAndLogs.SetOffset(0.5) // 上下平分
 
 	mainLayout := container.NewHSplit(leftPanel, listAndLogs)
 	mainLayout.SetOffset(0.3)
 
+	// 关闭窗口时先正常停止所有 frpc，避免服务端残留代理
+	window.SetCloseIntercept(func() {
+		if len(processes.running()) == 0 {
+			window.Close()
+			return
+		}
+		updateLogDisplay(logs, "正在停止所有 FRP 进程...")
+		go func() {
+			// 窗口即将关闭，停止失败的原因只能留在日志文件中
+			if err := processes.stopAll(); err != nil {
+				updateLogDisplay(logs, err.Error())
+				if logFile != nil {
+					logFile.WriteString("关闭窗口时停止 FRP 失败: " + err.Error() + "\n")
+				}
+			}
+			window.Close()
+		}()
+	})
+
 	window.SetContent(mainLayout)
 	window.ShowAndRun()
 }
 
-func maskIP(ip string) string {
-	// 假设IP地址是由四个数组组成，中间的第三个数字需要被隐藏
-	// 例如：192.168.123.456 -> 192.168.***.456
-	parts := strings.Split(ip, ".")
-	if len(parts) != 4 {
-		// 如果IP格式不对，返回原IP
-		return ip
-	}
-	// 替换中间部分
-	parts[2] = "^^^"
-	return strings.Join(parts, ".")
-}
-
 // 加载 .ico 文件为 Fyne 支持的图像资源
 func loadIconFromFile(path string) (fyne.Resource, error) {
 	// 打开 .ico 文件
@@ -698,5 +1058,8 @@ func initDirs() error {
 	if err != nil {
 		return fmt.Errorf("无法创建 src 目录: %v", err)
 	}
+	if err := os.MkdirAll(templatesDir, 0700); err != nil {
+		return fmt.Errorf("无法创建 templates 目录: %v", err)
+	}
 	return nil
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// profileOptions 启动器对配置的选项，不属于 frpc 配置，单独保存在 optionsDir 中
+type profileOptions struct {
+	EncryptAllProxies bool `toml:"encryptAllProxies,omitempty"` // 保存时为所有代理开启加密
+}
+
+// loadProfileOptions 读取配置的选项，文件不存在时返回零值
+func loadProfileOptions(dir, fileName string) (profileOptions, error) {
+	var opts profileOptions
+	err := loadTOMLSidecar(sidecarPath(dir, fileName, ".toml"), "配置选项", &opts)
+	return opts, err
+}
+
+// saveProfileOptions 保存配置的选项，全部为默认值时删除文件
+func saveProfileOptions(dir, fileName string, opts profileOptions) error {
+	path := sidecarPath(dir, fileName, ".toml")
+	if opts == (profileOptions{}) {
+		return saveTOMLSidecar(path, "配置选项", nil)
+	}
+	return saveTOMLSidecar(path, "配置选项", opts)
+}
+
+// apply 保存前按选项调整配置
+func (opts profileOptions) apply(cfg *ClientConfig) {
+	if opts.EncryptAllProxies {
+		cfg.encryptAllProxies()
+	}
+}
+
+// checkRaw 保存配置原文时不改写文本，只检查原文是否满足选项，无法解析的原文不检查
+func (opts profileOptions) checkRaw(format, text string) error {
+	if !opts.EncryptAllProxies {
+		return nil
+	}
+	rendered, _ := replaceEnvRefs(text)
+	cfg, err := decodeConfigAs(format, []byte(rendered))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, p := range cfg.Proxies {
+		if !p.Transport.UseEncryption {
+			names = append(names, p.Name)
+		}
+	}
+	if len(names) > 0 {
+		return fmt.Errorf("该配置开启了“加密所有代理”，请为以下代理设置 transport.useEncryption = true: %s", strings.Join(names, ", "))
+	}
+	return nil
+}
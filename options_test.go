@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestWriteProfileAppliesOptions(t *testing.T) {
+	dir, envDir, optionsDir := t.TempDir(), t.TempDir(), t.TempDir()
+	cfg := &ClientConfig{
+		ServerAddr: "1.2.3.4",
+		Proxies:    []Proxy{{Name: "ssh", Type: "tcp", LocalPort: 22, RemotePort: 6000}},
+		Options:    profileOptions{EncryptAllProxies: true},
+	}
+	if err := writeProfile(dir, envDir, optionsDir, "office.toml", cfg); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readProfile(dir, envDir, optionsDir, "office.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Options.EncryptAllProxies {
+		t.Error("选项没有保存")
+	}
+	if !got.Proxies[0].Transport.UseEncryption {
+		t.Error("保存时没有为代理开启加密")
+	}
+
+	// 没有代理的配置也要记住选项，不能从代理推断
+	empty := &ClientConfig{ServerAddr: "1.2.3.4", Options: profileOptions{EncryptAllProxies: true}}
+	if err := writeProfile(dir, envDir, optionsDir, "empty.yaml", empty); err != nil {
+		t.Fatal(err)
+	}
+	if opts, err := loadProfileOptions(optionsDir, "empty.toml"); err != nil || !opts.EncryptAllProxies {
+		t.Errorf("同名配置应共用选项，得到 %+v %v", opts, err)
+	}
+
+	// 关闭选项后删除选项文件
+	got.Options = profileOptions{}
+	if err := writeProfile(dir, envDir, optionsDir, "office.toml", got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(sidecarPath(optionsDir, "office.toml", ".toml")); !os.IsNotExist(err) {
+		t.Errorf("选项为默认值时应删除文件: %v", err)
+	}
+}
+
+func TestCheckRawEncryptAll(t *testing.T) {
+	text := "serverAddr = \"1.2.3.4\"\n\n[[proxies]]\nname = \"ssh\"\ntype = \"tcp\"\nlocalPort = {{ .Envs.PORT }}\n\n[[proxies]]\nname = \"web\"\ntype = \"tcp\"\ntransport.useEncryption = true\n"
+	if err := (profileOptions{}).checkRaw(formatTOML, text); err != nil {
+		t.Errorf("未开启选项时不应检查: %v", err)
+	}
+	err := profileOptions{EncryptAllProxies: true}.checkRaw(formatTOML, text)
+	if err == nil {
+		t.Fatal("未加密的代理应报错")
+	}
+	if want := "该配置开启了“加密所有代理”，请为以下代理设置 transport.useEncryption = true: ssh"; err.Error() != want {
+		t.Errorf("错误为 %q", err)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// splitList 将逗号或空白分隔的输入拆分为列表
+func splitList(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == '，' || r == ' ' || r == '\n' || r == '\t'
+	})
+}
+
+// parseHeaders 解析每行一个的 "Name: Value" 请求头
+func parseHeaders(text string) (map[string]string, error) {
+	var headers map[string]string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("请求头 %q 格式应为 Name: Value", line)
+		}
+		if headers == nil {
+			headers = map[string]string{}
+		}
+		headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+// parseParams 解析每行一个的 key=value 参数
+func parseParams(text string) (map[string]string, error) {
+	var params map[string]string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("参数 %q 格式应为 key=value", line)
+		}
+		if params == nil {
+			params = map[string]string{}
+		}
+		params[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return params, nil
+}
+
+func formatParams(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = key + "=" + params[key]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatHeaders(headers map[string]string) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = name + ": " + headers[name]
+	}
+	return strings.Join(lines, "\n")
+}
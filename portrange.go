@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxPortRangeSize 一个端口范围最多展开的端口数，每个端口都会生成一个代理
+const maxPortRangeSize = 100
+
+// parsePortRanges 解析 "6000-6010,7000" 形式的端口范围，按书写顺序展开
+func parsePortRanges(text string) ([]int, error) {
+	var ports []int
+	for _, part := range strings.Split(text, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil {
+			return nil, fmt.Errorf("无效的端口 %q", part)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(strings.TrimSpace(last)); err != nil {
+				return nil, fmt.Errorf("无效的端口范围 %q", part)
+			}
+		}
+		if start <= 0 || end > 65535 || start > end {
+			return nil, fmt.Errorf("端口范围 %q 应在 1-65535 之间且从小到大", part)
+		}
+		if len(ports)+end-start+1 > maxPortRangeSize {
+			return nil, fmt.Errorf("端口范围最多包含 %d 个端口，请拆分为多个代理", maxPortRangeSize)
+		}
+		for port := start; port <= end; port++ {
+			ports = append(ports, port)
+		}
+	}
+	if len(ports) == 0 {
+		return nil, fmt.Errorf("端口范围不能为空")
+	}
+	return ports, nil
+}
+
+// expandPortRange 把一个端口范围代理按顺序配对展开为多个单端口代理，名称为 "<name>-<本地端口>"
+func expandPortRange(p Proxy, localPorts, remotePorts []int) ([]Proxy, error) {
+	if len(localPorts) != len(remotePorts) {
+		return nil, fmt.Errorf("本地端口有 %d 个，远程端口有 %d 个，数量必须一致", len(localPorts), len(remotePorts))
+	}
+
+	proxies := make([]Proxy, len(localPorts))
+	for i := range localPorts {
+		proxies[i] = p
+		proxies[i].Name = fmt.Sprintf("%s-%d", p.Name, localPorts[i])
+		proxies[i].LocalPort = localPorts[i]
+		proxies[i].RemotePort = remotePorts[i]
+	}
+	return proxies, nil
+}
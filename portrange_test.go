@@ -0,0 +1,43 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePortRanges(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    []int
+		wantErr bool
+	}{
+		{text: "6000-6002,7000", want: []int{6000, 6001, 6002, 7000}},
+		{text: " 22 ", want: []int{22}},
+		{text: "6000-6099", want: rangeOf(6000, 6099)},
+		{text: "6000-6100", wantErr: true},
+		{text: "1-65535", wantErr: true},
+		{text: "6000-6050,7000-7050", wantErr: true},
+		{text: "6002-6000", wantErr: true},
+		{text: "0", wantErr: true},
+		{text: "abc", wantErr: true},
+		{text: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parsePortRanges(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: 错误为 %v，期望出错 %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: 得到 %v，期望 %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func rangeOf(start, end int) []int {
+	var ports []int
+	for port := start; port <= end; port++ {
+		ports = append(ports, port)
+	}
+	return ports
+}
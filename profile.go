@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// windowsReserved Windows 下不能用作文件名的设备名
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// maxProfileNameLen 配置名称的最大字节数，留出扩展名的空间
+const maxProfileNameLen = 200
+
+// sanitizeProfileName 把配置名称转换为 Windows 和 Linux 下都合法的文件名（不含扩展名）
+func sanitizeProfileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	// Windows 会忽略结尾的空格和点
+	name = strings.TrimRight(strings.TrimSpace(name), ". ")
+	if len(name) > maxProfileNameLen {
+		name = strings.ToValidUTF8(name[:maxProfileNameLen], "")
+	}
+	base, _, _ := strings.Cut(name, ".")
+	if windowsReserved[strings.ToUpper(base)] {
+		name = "_" + name
+	}
+	return name
+}
+
+// profileName 去掉配置文件名的扩展名
+func profileName(fileName string) string {
+	if isConfigFile(fileName) {
+		return strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	}
+	return fileName
+}
+
+// defaultProfileName 未填写名称时按服务器地址生成，例如 config_1.2.^^^.4
+func defaultProfileName(cfg *ClientConfig) string {
+	return sanitizeProfileName("config_" + maskIP(cfg.ServerAddr))
+}
+
+// findProfile 在 files 中查找同名配置，不区分格式和大小写，与 Windows 文件系统一致
+func findProfile(files []string, name string) (string, bool) {
+	for _, file := range files {
+		if strings.EqualFold(profileName(file), name) {
+			return file, true
+		}
+	}
+	return "", false
+}
+
+// uniqueProfileName 在 name 后追加序号，直到不与已有配置重名
+func uniqueProfileName(files []string, name string) string {
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s_%d", name, i)
+		if _, exists := findProfile(files, candidate); !exists {
+			return candidate
+		}
+	}
+}
+
+// mergeConfigs 把 src 的 visitor、proxy 和环境变量合并进 dst，保留 dst 的服务器设置，同名项以 src 为准
+func mergeConfigs(dst, src *ClientConfig) *ClientConfig {
+	merged := *dst
+	merged.Visitors = append([]Visitor(nil), dst.Visitors...)
+	for _, v := range src.Visitors {
+		replaced := false
+		for i := range merged.Visitors {
+			if merged.Visitors[i].Name == v.Name {
+				merged.Visitors[i] = v
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged.Visitors = append(merged.Visitors, v)
+		}
+	}
+	if len(src.Envs) > 0 {
+		merged.Envs = map[string]string{}
+		for name, val := range dst.Envs {
+			merged.Envs[name] = val
+		}
+		for name, val := range src.Envs {
+			merged.Envs[name] = val
+		}
+	}
+	merged.Proxies = append([]Proxy(nil), dst.Proxies...)
+	for _, p := range src.Proxies {
+		replaced := false
+		for i := range merged.Proxies {
+			if merged.Proxies[i].Name == p.Name {
+				merged.Proxies[i] = p
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged.Proxies = append(merged.Proxies, p)
+		}
+	}
+	return &merged
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// errReloadUnavailable frpc 管理接口无法使用，只能重启 frpc 使配置生效
+var errReloadUnavailable = errors.New("热重载不可用")
+
+// reloadClient 调用 frpc 管理接口的客户端，管理接口通常使用自签名证书
+var reloadClient = &http.Client{
+	Timeout:   10 * time.Second,
+	Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+}
+
+// adminAPI frpc webServer 管理接口的地址和认证信息
+type adminAPI struct {
+	baseURL  string // 如 http://127.0.0.1:7400
+	user     string
+	password string
+}
+
+// adminAPIOf 从配置的 webServer 中取出管理接口，未配置端口时返回 false
+func adminAPIOf(cfg *ClientConfig) (*adminAPI, bool) {
+	port, ok := lookupKey(cfg.Extra, toml.Key{"webServer", "port"})
+	if !ok {
+		return nil, false
+	}
+	n, ok := port.(int64)
+	if !ok || n <= 0 {
+		return nil, false
+	}
+	host, _ := lookupKey(cfg.Extra, toml.Key{"webServer", "addr"})
+	addr, _ := host.(string)
+	// frpc 默认只监听 127.0.0.1，监听所有地址时也从本机访问
+	switch addr {
+	case "", "0.0.0.0", "::":
+		addr = "127.0.0.1"
+	}
+	scheme := "http"
+	if certFile, _ := lookupKey(cfg.Extra, toml.Key{"webServer", "tls", "certFile"}); certFile != nil && certFile != "" {
+		scheme = "https"
+	}
+	api := &adminAPI{baseURL: scheme + "://" + net.JoinHostPort(trimBrackets(addr), strconv.FormatInt(n, 10))}
+	if user, ok := lookupKey(cfg.Extra, toml.Key{"webServer", "user"}); ok {
+		api.user, _ = user.(string)
+	}
+	if password, ok := lookupKey(cfg.Extra, toml.Key{"webServer", "password"}); ok {
+		api.password, _ = password.(string)
+	}
+	return api, true
+}
+
+// reloadError frpc 拒绝了新的配置，内容为 frpc 返回的校验错误
+type reloadError struct {
+	msg string
+}
+
+func (e *reloadError) Error() string {
+	return "frpc 拒绝了新配置: " + e.msg
+}
+
+// reloadFRPC 请求 frpc 重新加载配置文件。管理接口连不上、认证失败或不支持热重载时
+// 返回包装了 errReloadUnavailable 的错误，frpc 校验新配置失败时返回 *reloadError
+func reloadFRPC(ctx context.Context, client *http.Client, api *adminAPI) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, api.baseURL+"/api/reload?strictConfig=true", nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errReloadUnavailable, err)
+	}
+	if api.user != "" || api.password != "" {
+		req.SetBasicAuth(api.user, api.password)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: 无法连接管理接口: %v", errReloadUnavailable, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	msg := strings.TrimSpace(string(body))
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusBadRequest:
+		return &reloadError{msg: msg}
+	case http.StatusUnauthorized:
+		return fmt.Errorf("%w: 管理接口认证失败，请检查 webServer 的用户名和密码", errReloadUnavailable)
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return fmt.Errorf("%w: 当前 frpc 版本不支持热重载", errReloadUnavailable)
+	}
+	return fmt.Errorf("热重载失败: 状态码 %d: %s", resp.StatusCode, msg)
+}
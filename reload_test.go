@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestReloadFRPC(t *testing.T) {
+	tests := []struct {
+		status      int
+		wantErr     bool
+		rejected    bool // 应返回 *reloadError
+		unavailable bool // 应包装 errReloadUnavailable
+	}{
+		{status: http.StatusOK},
+		{status: http.StatusBadRequest, wantErr: true, rejected: true},
+		{status: http.StatusUnauthorized, wantErr: true, unavailable: true},
+		{status: http.StatusNotFound, wantErr: true, unavailable: true},
+		{status: http.StatusInternalServerError, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			// 模拟 frpc 管理接口
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/api/reload" {
+					t.Errorf("请求路径为 %s", r.URL.Path)
+				}
+				if user, password, ok := r.BasicAuth(); !ok || user != "admin" || password != "secret" {
+					t.Errorf("认证信息为 %q %q %v", user, password, ok)
+				}
+				w.WriteHeader(tt.status)
+				fmt.Fprintln(w, "proxy [web] remotePort conflict")
+			}))
+			defer srv.Close()
+
+			err := reloadFRPC(context.Background(), srv.Client(), &adminAPI{baseURL: srv.URL, user: "admin", password: "secret"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("错误为 %v，期望出错 %v", err, tt.wantErr)
+			}
+			var rejected *reloadError
+			if errors.As(err, &rejected) != tt.rejected {
+				t.Errorf("%v 是否为校验错误与期望不符", err)
+			}
+			if tt.rejected && rejected.msg != "proxy [web] remotePort conflict" {
+				t.Errorf("校验错误为 %q", rejected.msg)
+			}
+			if errors.Is(err, errReloadUnavailable) != tt.unavailable {
+				t.Errorf("%v 是否为热重载不可用与期望不符", err)
+			}
+		})
+	}
+}
+
+func TestReloadFRPCUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+	err := reloadFRPC(context.Background(), http.DefaultClient, &adminAPI{baseURL: url})
+	if !errors.Is(err, errReloadUnavailable) {
+		t.Errorf("连不上管理接口时应为热重载不可用，得到 %v", err)
+	}
+}
+
+func TestAdminAPIOf(t *testing.T) {
+	tests := []struct {
+		webServer string
+		want      *adminAPI
+	}{
+		{"", nil},
+		{`addr = "127.0.0.1"`, nil},
+		{"port = 7400", &adminAPI{baseURL: "http://127.0.0.1:7400"}},
+		{`addr = "0.0.0.0"` + "\nport = 7400", &adminAPI{baseURL: "http://127.0.0.1:7400"}},
+		{`addr = "::1"` + "\nport = 7400\nuser = \"admin\"\npassword = \"pw\"", &adminAPI{baseURL: "http://[::1]:7400", user: "admin", password: "pw"}},
+		{"port = 7400\n[webServer.tls]\ncertFile = \"server.crt\"", &adminAPI{baseURL: "https://127.0.0.1:7400"}},
+	}
+	for _, tt := range tests {
+		var extra map[string]any
+		if _, err := toml.Decode("[webServer]\n"+tt.webServer, &extra); err != nil {
+			t.Fatal(err)
+		}
+		got, ok := adminAPIOf(&ClientConfig{Extra: extra})
+		if ok != (tt.want != nil) || (ok && *got != *tt.want) {
+			t.Errorf("webServer %q: 得到 %+v %v，期望 %+v", tt.webServer, got, ok, tt.want)
+		}
+	}
+}
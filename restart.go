@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// frpc 退出后的重启方式
+const (
+	restartNever     = "never"      // 不重启
+	restartOnFailure = "on-failure" // 异常退出时重启
+	restartAlways    = "always"     // 除手动停止外总是重启
+)
+
+var restartModes = []string{restartNever, restartOnFailure, restartAlways}
+
+// restartPolicy 配置的重启策略，重启间隔从 InitialBackoff 开始每次翻倍，不超过 MaxBackoff
+type restartPolicy struct {
+	Mode                  string `toml:"mode"`
+	MaxRetries            int    `toml:"maxRetries"`            // 最多连续重启次数，0 表示不限
+	InitialBackoffSeconds int    `toml:"initialBackoffSeconds"` // 第一次重启前等待的秒数
+	MaxBackoffSeconds     int    `toml:"maxBackoffSeconds"`     // 重启间隔上限
+	StableSeconds         int    `toml:"stableSeconds"`         // 连续运行超过该时长后重新计算重启次数
+	StopTimeoutSeconds    int    `toml:"stopTimeoutSeconds"`    // 停止时等待 frpc 正常退出的秒数，超时后强制结束
+}
+
+// defaultRestartPolicy 未设置重启策略的配置不自动重启
+func defaultRestartPolicy() restartPolicy {
+	return restartPolicy{
+		Mode:                  restartNever,
+		MaxRetries:            5,
+		InitialBackoffSeconds: 1,
+		MaxBackoffSeconds:     60,
+		StableSeconds:         60,
+		StopTimeoutSeconds:    5,
+	}
+}
+
+func (p restartPolicy) validate() error {
+	switch p.Mode {
+	case restartNever, restartOnFailure, restartAlways:
+	default:
+		return fmt.Errorf("未知的重启方式 %q", p.Mode)
+	}
+	if p.MaxRetries < 0 {
+		return fmt.Errorf("最大重启次数不能为负数")
+	}
+	if p.InitialBackoffSeconds <= 0 {
+		return fmt.Errorf("首次重启间隔必须大于 0 秒")
+	}
+	if p.MaxBackoffSeconds < p.InitialBackoffSeconds {
+		return fmt.Errorf("最大重启间隔不能小于首次重启间隔")
+	}
+	if p.StableSeconds < 0 {
+		return fmt.Errorf("稳定运行时长不能为负数")
+	}
+	if p.StopTimeoutSeconds <= 0 {
+		return fmt.Errorf("停止等待时间必须大于 0 秒")
+	}
+	return nil
+}
+
+// shouldRestart 判断 frpc 退出后是否需要重启，failed 表示非零退出
+func (p restartPolicy) shouldRestart(failed bool) bool {
+	switch p.Mode {
+	case restartAlways:
+		return true
+	case restartOnFailure:
+		return failed
+	}
+	return false
+}
+
+// backoff 第 attempt 次重启前的等待时间，从 1 开始计数
+func (p restartPolicy) backoff(attempt int) time.Duration {
+	delay := time.Duration(p.InitialBackoffSeconds) * time.Second
+	limit := time.Duration(p.MaxBackoffSeconds) * time.Second
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+// stable 运行了 uptime 后退出时是否视为稳定运行过，稳定运行后重启次数重新计算
+func (p restartPolicy) stable(uptime time.Duration) bool {
+	return p.StableSeconds > 0 && uptime >= time.Duration(p.StableSeconds)*time.Second
+}
+
+// stopTimeout 停止时等待 frpc 正常退出的时间
+func (p restartPolicy) stopTimeout() time.Duration {
+	return time.Duration(p.StopTimeoutSeconds) * time.Second
+}
+
+// loadRestartPolicy 读取配置的重启策略，文件不存在时返回默认策略
+func loadRestartPolicy(dir, fileName string) (restartPolicy, error) {
+	policy := defaultRestartPolicy()
+	if err := loadTOMLSidecar(sidecarPath(dir, fileName, ".toml"), "重启策略", &policy); err != nil {
+		return policy, err
+	}
+	if err := policy.validate(); err != nil {
+		return policy, fmt.Errorf("重启策略无效: %v", err)
+	}
+	return policy, nil
+}
+
+// saveRestartPolicy 保存配置的重启策略，policy 为 nil 时删除文件恢复默认
+func saveRestartPolicy(dir, fileName string, policy *restartPolicy) error {
+	path := sidecarPath(dir, fileName, ".toml")
+	if policy == nil {
+		return saveTOMLSidecar(path, "重启策略", nil)
+	}
+	if err := policy.validate(); err != nil {
+		return err
+	}
+	return saveTOMLSidecar(path, "重启策略", policy)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestartPolicyBackoff(t *testing.T) {
+	p := defaultRestartPolicy()
+	want := []time.Duration{1, 2, 4, 8, 16, 32, 60, 60}
+	for i, w := range want {
+		if got := p.backoff(i + 1); got != w*time.Second {
+			t.Errorf("第 %d 次重启等待 %s，期望 %s", i+1, got, w*time.Second)
+		}
+	}
+	// 次数很大时不能溢出
+	if got := p.backoff(1000); got != 60*time.Second {
+		t.Errorf("重启间隔应不超过上限，得到 %s", got)
+	}
+
+	p.InitialBackoffSeconds, p.MaxBackoffSeconds = 3, 10
+	if got := p.backoff(3); got != 10*time.Second {
+		t.Errorf("重启间隔应截断到上限，得到 %s", got)
+	}
+}
+
+func TestRestartPolicyShouldRestart(t *testing.T) {
+	tests := []struct {
+		mode   string
+		failed bool
+		want   bool
+	}{
+		{restartNever, true, false},
+		{restartNever, false, false},
+		{restartOnFailure, true, true},
+		{restartOnFailure, false, false},
+		{restartAlways, true, true},
+		{restartAlways, false, true},
+	}
+	for _, tt := range tests {
+		p := restartPolicy{Mode: tt.mode}
+		if got := p.shouldRestart(tt.failed); got != tt.want {
+			t.Errorf("%s 模式 failed=%v 时应为 %v", tt.mode, tt.failed, tt.want)
+		}
+	}
+}
+
+func TestRestartPolicyStable(t *testing.T) {
+	p := defaultRestartPolicy()
+	if p.stable(59 * time.Second) {
+		t.Error("运行不足 60 秒不应视为稳定")
+	}
+	if !p.stable(60 * time.Second) {
+		t.Error("运行满 60 秒应视为稳定")
+	}
+	p.StableSeconds = 0
+	if p.stable(time.Hour) {
+		t.Error("StableSeconds 为 0 时不重新计算重启次数")
+	}
+}
+
+func TestRestartPolicySaveLoad(t *testing.T) {
+	dir := t.TempDir()
+	policy, err := loadRestartPolicy(dir, "a.toml")
+	if err != nil || policy != defaultRestartPolicy() {
+		t.Fatalf("没有策略文件时应返回默认策略: %+v, %v", policy, err)
+	}
+	want := defaultRestartPolicy()
+	want.Mode = restartAlways
+	want.MaxRetries = 0
+	if err := saveRestartPolicy(dir, "a.toml", &want); err != nil {
+		t.Fatal(err)
+	}
+	// 同名的其他格式配置共用重启策略
+	if policy, err = loadRestartPolicy(dir, "a.yaml"); err != nil || policy != want {
+		t.Errorf("读取的策略 %+v 与保存的不一致: %v", policy, err)
+	}
+	bad := want
+	bad.MaxBackoffSeconds = 0
+	if err := saveRestartPolicy(dir, "a.toml", &bad); err == nil {
+		t.Error("无效的策略不应保存")
+	}
+	if err := saveRestartPolicy(dir, "a.toml", nil); err != nil {
+		t.Fatal(err)
+	}
+	if policy, _ = loadRestartPolicy(dir, "a.toml"); policy != defaultRestartPolicy() {
+		t.Errorf("删除后应恢复默认策略: %+v", policy)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+// sidecarPath 配置的附属文件，如环境变量表和重启策略。附属文件按配置名称存放在各自的目录中，
+// 不同格式的同名配置共用
+func sidecarPath(dir, fileName, ext string) string {
+	return filepath.Join(dir, profileName(fileName)+ext)
+}
+
+// readSidecar 读取附属文件，文件不存在时返回 nil，what 为错误信息中的名称
+func readSidecar(path, what string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取%s失败: %v", what, err)
+	}
+	return data, nil
+}
+
+// writeSidecar 写入附属文件，data 为空时删除文件恢复默认。附属文件中可能有密钥，只允许当前用户读写
+func writeSidecar(path, what string, data []byte) error {
+	if len(data) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("删除%s失败: %v", what, err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("创建%s目录失败: %v", what, err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("保存%s失败: %v", what, err)
+	}
+	return nil
+}
+
+// loadTOMLSidecar 把 TOML 格式的附属文件解析到 v，文件不存在时保持 v 不变
+func loadTOMLSidecar(path, what string, v any) error {
+	data, err := readSidecar(path, what)
+	if err != nil || data == nil {
+		return err
+	}
+	if _, err := toml.Decode(string(data), v); err != nil {
+		return fmt.Errorf("解析%s失败: %v", what, err)
+	}
+	return nil
+}
+
+// saveTOMLSidecar 把 v 编码为 TOML 写入附属文件，v 为 nil 时删除文件
+func saveTOMLSidecar(path, what string, v any) error {
+	if v == nil {
+		return writeSidecar(path, what, nil)
+	}
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+		return fmt.Errorf("编码%s失败: %v", what, err)
+	}
+	return writeSidecar(path, what, buf.Bytes())
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// processState frpc 进程的运行状态
+type processState int
+
+const (
+	stateStopped  processState = iota // 未运行或已手动停止
+	stateStarting                     // 已启动进程，尚未登录到服务器
+	stateRunning                      // 已登录到服务器
+	stateCrashed                      // 非手动停止的异常退出
+	stateBackoff                      // 已退出，等待按重启策略重启
+)
+
+func (s processState) String() string {
+	switch s {
+	case stateStarting:
+		return "启动中"
+	case stateRunning:
+		return "运行中"
+	case stateCrashed:
+		return "已崩溃"
+	case stateBackoff:
+		return "等待重启"
+	}
+	return "已停止"
+}
+
+// active 进程是否仍在运行，等待重启也算在内
+func (s processState) active() bool {
+	return s == stateStarting || s == stateRunning || s == stateBackoff
+}
+
+// frpc 登录服务器成功后输出 "login to server success"
+const frpcReadyLog = "login to server success"
+
+// launchInfo 启动 frpc 时的管理接口和注入的环境变量。frpc 热重载不会更新 webServer，
+// 也只会用启动时的环境变量渲染配置，修改后需要重启才能生效
+type launchInfo struct {
+	admin *adminAPI // 未配置 webServer 时为 nil
+	envs  map[string]string
+}
+
+// managedProcess 一个配置对应的 frpc 进程
+type managedProcess struct {
+	launch   func() *exec.Cmd // 每次启动和重启都构建新的命令
+	policy   restartPolicy
+	launched launchInfo // 启动时的管理接口和环境变量
+	cmd      *exec.Cmd
+	state    processState
+	stopping bool // 手动停止，退出时不算崩溃也不重启
+	started  time.Time
+	restarts int           // 连续重启的次数
+	timer    *time.Timer   // 等待重启的定时器
+	done     chan struct{} // 本次运行的进程退出后关闭
+	health   healthStatus
+}
+
+// supervisor 按配置文件管理多个 frpc 进程，日志协程和等待协程会并发访问
+type supervisor struct {
+	mu    sync.Mutex
+	procs map[string]*managedProcess
+
+	// onLine 收到一行 frpc 输出，onState 进程状态变化，reason 为需要记入日志的原因，可能为空
+	onLine  func(name, line string)
+	onState func(name string, state processState, reason string)
+}
+
+func newSupervisor() *supervisor {
+	return &supervisor{procs: map[string]*managedProcess{}}
+}
+
+// start 为配置启动 frpc，退出后按 policy 重启，同一配置已在运行时返回错误
+func (s *supervisor) start(name string, launch func() *exec.Cmd, policy restartPolicy, launched launchInfo) error {
+	s.mu.Lock()
+	if p, ok := s.procs[name]; ok && p.state.active() {
+		s.mu.Unlock()
+		return fmt.Errorf("%s 已在运行", name)
+	}
+	p := &managedProcess{launch: launch, policy: policy, launched: launched}
+	if err := s.run(name, p); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.procs[name] = p
+	s.mu.Unlock()
+	s.notify(name, stateStarting, "")
+	return nil
+}
+
+// run 构建并启动一次 frpc，调用时需持有锁
+func (s *supervisor) run(name string, p *managedProcess) error {
+	cmd := p.launch()
+	// stdout 和 stderr 使用同一个 writer，exec 保证同一时间只有一个协程写入
+	out := &lineWriter{onLine: func(line string) { s.handleLine(name, p, line) }}
+	cmd.Stdout = out
+	cmd.Stderr = out
+	p.started = time.Now()
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("启动 FRP 失败: %v", err)
+	}
+	done := make(chan struct{})
+	p.cmd = cmd
+	p.done = done
+	p.state = stateStarting
+	p.health.reset()
+
+	go func() {
+		err := cmd.Wait()
+		out.flush()
+		s.exited(name, p, err)
+		close(done)
+	}()
+	return nil
+}
+
+// exited 处理 frpc 退出：手动停止的直接结束，否则按重启策略安排重启
+func (s *supervisor) exited(name string, p *managedProcess, err error) {
+	s.mu.Lock()
+	if p.stopping {
+		p.state = stateStopped
+		s.mu.Unlock()
+		s.notify(name, stateStopped, "FRP 已停止")
+		return
+	}
+
+	failed := err != nil
+	reason := "FRP 已退出"
+	state := stateStopped
+	if failed {
+		reason = fmt.Sprintf("FRP 运行中断: %v", err)
+		state = stateCrashed
+	}
+	// 稳定运行过一段时间后的退出视为新的故障，重新计算重启次数
+	if p.policy.stable(time.Since(p.started)) {
+		p.restarts = 0
+	}
+	if !p.policy.shouldRestart(failed) {
+		p.state = state
+		s.mu.Unlock()
+		s.notify(name, state, reason)
+		return
+	}
+	if p.policy.MaxRetries > 0 && p.restarts >= p.policy.MaxRetries {
+		p.state = state
+		s.mu.Unlock()
+		s.notify(name, state, fmt.Sprintf("%s，已连续重启 %d 次，不再重启", reason, p.restarts))
+		return
+	}
+
+	p.restarts++
+	attempt := p.restarts
+	delay := p.policy.backoff(attempt)
+	p.state = stateBackoff
+	p.timer = time.AfterFunc(delay, func() { s.restart(name, p) })
+	s.mu.Unlock()
+	s.notify(name, stateBackoff, fmt.Sprintf("%s，%s 后第 %d 次重启", reason, delay, attempt))
+}
+
+// restart 等待结束后重新启动 frpc，期间被手动停止或重新启动时放弃
+func (s *supervisor) restart(name string, p *managedProcess) {
+	s.mu.Lock()
+	if p.stopping || p.state != stateBackoff || s.procs[name] != p {
+		s.mu.Unlock()
+		return
+	}
+	if err := s.run(name, p); err != nil {
+		s.mu.Unlock()
+		s.exited(name, p, err)
+		return
+	}
+	attempt := p.restarts
+	s.mu.Unlock()
+	s.notify(name, stateStarting, fmt.Sprintf("第 %d 次重启 FRP", attempt))
+}
+
+// setPolicy 修改运行中配置的重启策略，下次退出时生效
+func (s *supervisor) setPolicy(name string, policy restartPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.procs[name]; ok {
+		p.policy = policy
+	}
+}
+
+// handleLine 根据 frpc 输出更新登录和健康检查状态
+func (s *supervisor) handleLine(name string, p *managedProcess, line string) {
+	if ev, ok := parseHealthEvent(line); ok {
+		p.health.update(ev)
+	}
+	ready := false
+	if strings.Contains(line, frpcReadyLog) {
+		s.mu.Lock()
+		if p.state == stateStarting {
+			p.state = stateRunning
+			ready = true
+		}
+		s.mu.Unlock()
+	}
+	if s.onLine != nil {
+		s.onLine(name, line)
+	}
+	if ready {
+		s.notify(name, stateRunning, "")
+	}
+}
+
+func (s *supervisor) notify(name string, state processState, reason string) {
+	if s.onState != nil {
+		s.onState(name, state, reason)
+	}
+}
+
+// stop 通知 frpc 正常退出，超过策略中的等待时间后强制结束；等待重启时取消重启，未运行时返回错误
+func (s *supervisor) stop(name string) error {
+	_, err := s.stopProcess(name)
+	return err
+}
+
+// stopProcess 停止配置对应的 frpc，返回的通道在进程退出后关闭
+func (s *supervisor) stopProcess(name string) (<-chan struct{}, error) {
+	s.mu.Lock()
+	p, ok := s.procs[name]
+	if !ok || !p.state.active() {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%s 没有运行中的 FRP 进程", name)
+	}
+	if p.state == stateBackoff {
+		p.stopping = true
+		p.timer.Stop()
+		p.state = stateStopped
+		s.mu.Unlock()
+		s.notify(name, stateStopped, "FRP 已停止，取消重启")
+		return closedChan, nil
+	}
+	if p.stopping {
+		// 已在停止中，等待上一次停止的结果
+		s.mu.Unlock()
+		return p.done, nil
+	}
+	p.stopping = true
+	cmd, done, timeout := p.cmd, p.done, p.policy.stopTimeout()
+	s.mu.Unlock()
+
+	if err := interruptProcess(cmd); err != nil {
+		s.notify(name, s.state(name), fmt.Sprintf("无法正常停止 FRP: %v，强制结束", err))
+		if err := killProcess(cmd); err != nil {
+			return done, fmt.Errorf("强制停止 %s 失败: %v", name, err)
+		}
+		return done, nil
+	}
+	go func() {
+		select {
+		case <-done:
+		case <-time.After(timeout):
+			s.notify(name, s.state(name), fmt.Sprintf("FRP %s 内未退出，强制结束", timeout))
+			if err := killProcess(cmd); err != nil {
+				s.notify(name, s.state(name), fmt.Sprintf("强制停止 FRP 失败: %v", err))
+			}
+		}
+	}()
+	return done, nil
+}
+
+// stopAll 停止所有运行中的 frpc，并等待它们全部退出
+func (s *supervisor) stopAll() error {
+	var errs []error
+	var waits []<-chan struct{}
+	for _, name := range s.running() {
+		done, err := s.stopProcess(name)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if done != nil {
+			waits = append(waits, done)
+		}
+	}
+	for _, done := range waits {
+		<-done
+	}
+	return errors.Join(errs...)
+}
+
+// launchInfo 返回运行中配置启动时的信息，未运行时返回 false
+func (s *supervisor) launchInfo(name string) (launchInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.procs[name]; ok && p.state.active() {
+		return p.launched, true
+	}
+	return launchInfo{}, false
+}
+
+// state 返回配置对应进程的状态，从未启动过的配置为已停止
+func (s *supervisor) state(name string) processState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.procs[name]; ok {
+		return p.state
+	}
+	return stateStopped
+}
+
+// running 返回运行中的配置，按名称排序
+func (s *supervisor) running() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name, p := range s.procs {
+		if p.state.active() {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// healthSummary 汇总运行中配置的健康检查失败情况，全部正常时为空
+func (s *supervisor) healthSummary() string {
+	var lines []string
+	for _, name := range s.running() {
+		s.mu.Lock()
+		p := s.procs[name]
+		s.mu.Unlock()
+		if summary := p.health.summary(); summary != "" {
+			lines = append(lines, name+" "+summary)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// closedChan 已关闭的通道，用于无需等待的停止
+var closedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// lineWriter 把进程输出按行切分后回调
+type lineWriter struct {
+	buf    []byte
+	onLine func(line string)
+}
+
+func (w *lineWriter) Write(data []byte) (int, error) {
+	w.buf = append(w.buf, data...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.onLine(strings.TrimRight(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	return len(data), nil
+}
+
+// flush 输出最后一行不完整的内容
+func (w *lineWriter) flush() {
+	if len(w.buf) > 0 {
+		w.onLine(strings.TrimRight(string(w.buf), "\r"))
+		w.buf = nil
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stateEvent 一次状态通知
+type stateEvent struct {
+	state  processState
+	reason string
+}
+
+// newTestSupervisor 创建记录状态通知的 supervisor，p 登记为配置 name 的进程
+func newTestSupervisor(t *testing.T, name string, p *managedProcess) (*supervisor, *[]stateEvent) {
+	s := newSupervisor()
+	var events []stateEvent
+	s.onState = func(_ string, state processState, reason string) {
+		events = append(events, stateEvent{state, reason})
+	}
+	s.procs[name] = p
+	// 测试只检查安排的重启，不等定时器触发
+	t.Cleanup(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if p.timer != nil {
+			p.timer.Stop()
+		}
+	})
+	return s, &events
+}
+
+func TestSupervisorExited(t *testing.T) {
+	errExit := errors.New("exit status 1")
+	tests := []struct {
+		name     string
+		mode     string
+		restarts int     // 退出前已连续重启的次数
+		uptime   float64 // 本次运行的秒数
+		stopping bool
+		err      error
+		state    processState
+		restart  int    // 退出后的连续重启次数
+		reason   string // 通知原因中应包含的内容
+	}{
+		{name: "不重启", mode: restartNever, err: errExit, state: stateCrashed, reason: "FRP 运行中断"},
+		{name: "正常退出不重启", mode: restartOnFailure, state: stateStopped, reason: "FRP 已退出"},
+		{name: "手动停止", mode: restartAlways, stopping: true, err: errExit, state: stateStopped, reason: "FRP 已停止"},
+		{name: "异常退出后重启", mode: restartOnFailure, err: errExit, state: stateBackoff, restart: 1, reason: "1s 后第 1 次重启"},
+		{name: "正常退出也重启", mode: restartAlways, state: stateBackoff, restart: 1, reason: "1s 后第 1 次重启"},
+		{name: "重启间隔翻倍", mode: restartAlways, restarts: 3, err: errExit, state: stateBackoff, restart: 4, reason: "8s 后第 4 次重启"},
+		{name: "重启间隔不超过上限", mode: restartAlways, restarts: 4, err: errExit, state: stateBackoff, restart: 5, reason: "10s 后第 5 次重启"},
+		{name: "达到最大重启次数", mode: restartAlways, restarts: 5, err: errExit, state: stateCrashed, restart: 5, reason: "已连续重启 5 次，不再重启"},
+		{name: "稳定运行后重新计算", mode: restartAlways, restarts: 5, uptime: 120, err: errExit, state: stateBackoff, restart: 1, reason: "1s 后第 1 次重启"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := defaultRestartPolicy()
+			policy.Mode = tt.mode
+			policy.MaxBackoffSeconds = 10
+			p := &managedProcess{
+				policy:   policy,
+				state:    stateRunning,
+				stopping: tt.stopping,
+				restarts: tt.restarts,
+				started:  time.Now().Add(-time.Duration(tt.uptime * float64(time.Second))),
+			}
+			s, events := newTestSupervisor(t, "a.toml", p)
+			s.exited("a.toml", p, tt.err)
+
+			if p.state != tt.state || p.restarts != tt.restart {
+				t.Errorf("状态 %s、重启次数 %d，期望 %s、%d", p.state, p.restarts, tt.state, tt.restart)
+			}
+			if (p.timer != nil) != (tt.state == stateBackoff) {
+				t.Errorf("只有等待重启时才安排重启，timer=%v", p.timer)
+			}
+			if len(*events) != 1 || (*events)[0].state != tt.state || !strings.Contains((*events)[0].reason, tt.reason) {
+				t.Errorf("通知 %+v，期望包含 %q", *events, tt.reason)
+			}
+		})
+	}
+}
+
+func TestSupervisorRestartFailedLaunch(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "frpc")
+	launches := 0
+	policy := defaultRestartPolicy()
+	policy.Mode = restartOnFailure
+	p := &managedProcess{
+		launch: func() *exec.Cmd {
+			launches++
+			return exec.Command(missing)
+		},
+		policy:   policy,
+		state:    stateBackoff,
+		restarts: 1,
+		started:  time.Now(),
+	}
+	s, events := newTestSupervisor(t, "a.toml", p)
+	s.restart("a.toml", p)
+
+	// 启动失败按异常退出处理，继续安排下一次重启
+	if launches != 1 {
+		t.Fatalf("应尝试启动一次，实际 %d 次", launches)
+	}
+	if p.state != stateBackoff || p.restarts != 2 || p.timer == nil {
+		t.Errorf("启动失败后应安排第 2 次重启: 状态 %s、重启次数 %d", p.state, p.restarts)
+	}
+	if len(*events) != 1 || !strings.Contains((*events)[0].reason, "启动 FRP 失败") || !strings.Contains((*events)[0].reason, "2s 后第 2 次重启") {
+		t.Errorf("通知 %+v", *events)
+	}
+
+	// 达到最大次数后不再重启
+	p.timer.Stop()
+	p.restarts = policy.MaxRetries
+	*events = nil
+	s.restart("a.toml", p)
+	if p.state != stateCrashed || launches != 2 {
+		t.Errorf("达到最大重启次数后应为已崩溃，得到 %s", p.state)
+	}
+}
+
+func TestSupervisorRestartCancelled(t *testing.T) {
+	launches := 0
+	p := &managedProcess{
+		launch: func() *exec.Cmd {
+			launches++
+			return exec.Command("frpc")
+		},
+		policy: defaultRestartPolicy(),
+		state:  stateStopped,
+	}
+	s, events := newTestSupervisor(t, "a.toml", p)
+	// 等待期间已被手动停止
+	s.restart("a.toml", p)
+	// 已被重新启动的新进程替换
+	p.state = stateBackoff
+	s.procs["a.toml"] = &managedProcess{}
+	s.restart("a.toml", p)
+	if launches != 0 || len(*events) != 0 {
+		t.Errorf("已取消的重启不应启动 frpc: 启动 %d 次，通知 %+v", launches, *events)
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+)
+
+// tokenKind TOML 高亮的标记类型
+type tokenKind int
+
+const (
+	tokenText tokenKind = iota
+	tokenComment
+	tokenTable
+	tokenKey
+	tokenString
+	tokenValue
+)
+
+// tomlToken 一行中的一段高亮，start/end 为字符（rune）下标
+type tomlToken struct {
+	start, end int
+	kind       tokenKind
+}
+
+// highlightTOMLLine 按行给 TOML 分段着色，跨行的多行字符串按普通文本处理
+func highlightTOMLLine(line string) []tomlToken {
+	runes := []rune(line)
+	var tokens []tomlToken
+	i := 0
+	for i < len(runes) && (runes[i] == ' ' || runes[i] == '\t') {
+		i++
+	}
+	if i == len(runes) {
+		return nil
+	}
+
+	switch runes[i] {
+	case '#':
+		return []tomlToken{{start: i, end: len(runes), kind: tokenComment}}
+	case '[':
+		end := i
+		for end < len(runes) && runes[end] != '#' {
+			end++
+		}
+		header := strings.TrimRight(string(runes[i:end]), " \t")
+		tokens = append(tokens, tomlToken{start: i, end: i + utf8.RuneCountInString(header), kind: tokenTable})
+		if end < len(runes) {
+			tokens = append(tokens, tomlToken{start: end, end: len(runes), kind: tokenComment})
+		}
+		return tokens
+	}
+
+	// 键：到第一个不在引号内的 = 为止
+	keyStart := i
+	var quote rune
+	for ; i < len(runes); i++ {
+		r := runes[i]
+		if quote != 0 {
+			if r == quote {
+				quote = 0
+			}
+			continue
+		}
+		if r == '"' || r == '\'' {
+			quote = r
+		} else if r == '=' {
+			break
+		}
+	}
+	keyEnd := i
+	for keyEnd > keyStart && (runes[keyEnd-1] == ' ' || runes[keyEnd-1] == '\t') {
+		keyEnd--
+	}
+	tokens = append(tokens, tomlToken{start: keyStart, end: keyEnd, kind: tokenKey})
+	if i == len(runes) {
+		return tokens
+	}
+	i++ // 跳过 =
+
+	// 值：字符串、注释、数字/布尔/日期等
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case r == '#':
+			return append(tokens, tomlToken{start: i, end: len(runes), kind: tokenComment})
+		case r == '"' || r == '\'':
+			start := i
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if r == '"' && runes[i] == '\\' {
+					i++
+				}
+			}
+			if i < len(runes) {
+				i++
+			}
+			if i > len(runes) {
+				i = len(runes)
+			}
+			tokens = append(tokens, tomlToken{start: start, end: i, kind: tokenString})
+		case strings.ContainsRune(" \t[]{},=", r):
+			i++
+		default:
+			start := i
+			for i < len(runes) && !strings.ContainsRune(" \t[]{},#\"'", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, tomlToken{start: start, end: i, kind: tokenValue})
+		}
+	}
+	return tokens
+}
+
+// syntaxError 带行列号的语法错误，行列均从 1 开始，未知位置时为 0
+type syntaxError struct {
+	line, col int
+	msg       string
+}
+
+func (e *syntaxError) Error() string {
+	if e.line == 0 {
+		return e.msg
+	}
+	return fmt.Sprintf("第 %d 行第 %d 列: %s", e.line, e.col, e.msg)
+}
+
+// replaceEnvRefs 把环境变量引用换成占位值 0，返回替换后的文本，
+// 以及把替换后文本中的字节偏移换算回原文偏移的函数
+func replaceEnvRefs(text string) (string, func(int) int) {
+	refs := envRefPattern.FindAllStringIndex(text, -1)
+	if refs == nil {
+		return text, func(offset int) int { return offset }
+	}
+	var b strings.Builder
+	starts := make([]int, len(refs)) // 各占位值在替换后文本中的位置
+	last := 0
+	for i, ref := range refs {
+		b.WriteString(text[last:ref[0]])
+		starts[i] = b.Len()
+		b.WriteString("0")
+		last = ref[1]
+	}
+	b.WriteString(text[last:])
+	return b.String(), func(offset int) int {
+		// 每个占位值比原来的引用短，偏移需要加上之前所有引用缩短的长度
+		shift := 0
+		for i, ref := range refs {
+			if offset < starts[i] {
+				break
+			}
+			if offset == starts[i] {
+				return ref[0]
+			}
+			shift += ref[1] - ref[0] - 1
+		}
+		return offset + shift
+	}
+}
+
+// checkSyntax 解析原始配置，返回语法错误和 frpc 不认识的键。
+// {{ .Envs.NAME }} 由 frpc 在解析前替换，这里先换成占位值再解析，错误位置按原文计算
+func checkSyntax(format, text string) (*syntaxError, []string) {
+	rendered, origOffset := replaceEnvRefs(text)
+	if format != formatYAML && format != formatJSON {
+		var raw map[string]any
+		_, err := toml.Decode(rendered, &raw)
+		var pe toml.ParseError
+		if errors.As(err, &pe) {
+			// 行列都按出错位置的字节偏移计算，错误落在换行符上时 Position.Line 会多算一行
+			before := text[:min(origOffset(pe.Position.Start), len(text))]
+			line := strings.Count(before, "\n") + 1
+			col := utf8.RuneCountInString(before[strings.LastIndex(before, "\n")+1:]) + 1
+			msg := pe.Message
+			if msg == "" {
+				// 部分错误只有内部 err，从完整信息中截掉 "toml: line N (last key ...): " 前缀
+				msg = pe.Error()
+				if _, rest, ok := strings.Cut(msg, ": "); ok {
+					if i := strings.Index(rest, ": "); i >= 0 {
+						msg = rest[i+2:]
+					}
+				}
+			}
+			return &syntaxError{line: line, col: col, msg: msg}, nil
+		}
+		if err != nil {
+			return &syntaxError{msg: err.Error()}, nil
+		}
+		return nil, unknownKeys(raw)
+	}
+	raw, err := decodeRawAs(format, []byte(rendered))
+	if err != nil {
+		return &syntaxError{msg: err.Error()}, nil
+	}
+	return nil, unknownKeys(raw)
+}
+
+// keySchema frpc 客户端配置的键，值为 nil 表示叶子，含 "*" 表示接受任意子键
+type keySchema map[string]keySchema
+
+var anyKeys = keySchema{"*": nil}
+
+var proxyTransportKeys = keySchema{
+	"useEncryption": nil, "useCompression": nil, "bandwidthLimit": nil, "bandwidthLimitMode": nil, "proxyProtocolVersion": nil,
+}
+
+// frpcKeys 按 frpc v0.5x 的 TOML 配置整理
+var frpcKeys = keySchema{
+	"serverAddr": nil, "serverPort": nil, "user": nil, "natHoleStunServer": nil, "dnsServer": nil,
+	"loginFailExit": nil, "start": nil, "udpPacketSize": nil, "includes": nil,
+	"metadatas": anyKeys, "featureGates": anyKeys,
+	"auth": {
+		"method": nil, "additionalScopes": nil, "token": nil, "tokenSource": anyKeys,
+		"oidc": {
+			"clientID": nil, "clientSecret": nil, "audience": nil, "scope": nil, "tokenEndpointURL": nil,
+			"additionalEndpointParams": anyKeys, "trustedCaFile": nil, "insecureSkipVerify": nil, "proxyURL": nil,
+		},
+	},
+	"log": {"to": nil, "level": nil, "maxDays": nil, "disablePrintColor": nil},
+	"webServer": {
+		"addr": nil, "port": nil, "user": nil, "password": nil, "assetsDir": nil, "pprofEnable": nil,
+		"tls": {"certFile": nil, "keyFile": nil},
+	},
+	"transport": {
+		"protocol": nil, "dialServerTimeout": nil, "dialServerKeepalive": nil, "connectServerLocalIP": nil,
+		"proxyURL": nil, "poolCount": nil, "tcpMux": nil, "tcpMuxKeepaliveInterval": nil,
+		"heartbeatInterval": nil, "heartbeatTimeout": nil,
+		"quic": {"keepalivePeriod": nil, "maxIdleTimeout": nil, "maxIncomingStreams": nil},
+		"tls":  {"enable": nil, "disableCustomTLSFirstByte": nil, "certFile": nil, "keyFile": nil, "trustedCaFile": nil, "serverName": nil},
+	},
+	"virtualNet": {"address": nil},
+	"proxies": {
+		"name": nil, "type": nil, "enabled": nil, "annotations": anyKeys, "metadatas": anyKeys,
+		"transport":    proxyTransportKeys,
+		"loadBalancer": {"group": nil, "groupKey": nil},
+		"healthCheck":  {"type": nil, "timeoutSeconds": nil, "maxFailed": nil, "intervalSeconds": nil, "path": nil, "httpHeaders": nil},
+		"localIP":      nil, "localPort": nil, "remotePort": nil, "secretKey": nil, "allowUsers": nil,
+		"customDomains": nil, "subdomain": nil, "locations": nil, "httpUser": nil, "httpPassword": nil,
+		"hostHeaderRewrite": nil, "requestHeaders": {"set": anyKeys}, "responseHeaders": {"set": anyKeys},
+		"routeByHTTPUser": nil, "multiplexer": nil, "natTraversal": anyKeys,
+		// 插件参数随插件类型变化，不逐一检查
+		"plugin": anyKeys,
+	},
+	"visitors": {
+		"name": nil, "type": nil, "enabled": nil, "transport": proxyTransportKeys,
+		"secretKey": nil, "serverUser": nil, "serverName": nil, "bindAddr": nil, "bindPort": nil,
+		"protocol": nil, "keepTunnelOpen": nil, "maxRetriesAnHour": nil, "minRetryInterval": nil,
+		"fallbackTo": nil, "fallbackTimeoutMs": nil, "natTraversal": anyKeys, "plugin": anyKeys,
+	},
+}
+
+// unknownKeys 返回 frpc 不认识的键路径，如 proxies[0].locaPort
+func unknownKeys(raw map[string]any) []string {
+	var keys []string
+	var walk func(m map[string]any, schema keySchema, prefix string)
+	walk = func(m map[string]any, schema keySchema, prefix string) {
+		if _, ok := schema["*"]; ok {
+			return
+		}
+		for key, val := range m {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			sub, ok := schema[key]
+			if !ok {
+				keys = append(keys, path)
+				continue
+			}
+			if sub == nil {
+				continue
+			}
+			switch val := val.(type) {
+			case map[string]any:
+				walk(val, sub, path)
+			case []map[string]any:
+				for i, item := range val {
+					walk(item, sub, fmt.Sprintf("%s[%d]", path, i))
+				}
+			}
+		}
+	}
+	walk(raw, frpcKeys, "")
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckSyntaxPosition(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		line, col int
+	}{
+		{"缺少值", "serverAddr = \"a\"\nserverPort = \n", 2, 14},
+		{"制表符按一列计算", "serverAddr = \"a\"\n\tserverPort = \n", 2, 15},
+		{"环境变量引用之后", "serverAddr = \"{{ .Envs.ADDR }}\"\nserverPort = {{ .Envs.PORT }} x\n", 2, 30},
+		{"同一行多个引用", "auth.token = \"{{ .Envs.A }}{{ .Envs.B }}\" ]\n", 1, 42},
+	}
+	for _, tt := range tests {
+		err, _ := checkSyntax(formatTOML, tt.text)
+		if err == nil {
+			t.Errorf("%s: 没有报告语法错误", tt.name)
+			continue
+		}
+		if err.line != tt.line || err.col != tt.col {
+			t.Errorf("%s: 错误位置为第 %d 行第 %d 列，期望第 %d 行第 %d 列 (%s)", tt.name, err.line, err.col, tt.line, tt.col, err.msg)
+		}
+	}
+}
+
+func TestCheckSyntaxUnknownKeys(t *testing.T) {
+	text := "serverAddr = \"{{ .Envs.ADDR }}\"\nserverPort = {{ .Envs.PORT }}\nfoo = 1\n\n[[proxies]]\nname = \"web\"\nbar = true\n"
+	err, keys := checkSyntax(formatTOML, text)
+	if err != nil {
+		t.Fatalf("不应报告语法错误: %v", err)
+	}
+	if want := []string{"foo", "proxies[0].bar"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("未知配置项为 %v，期望 %v", keys, want)
+	}
+}
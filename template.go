@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// templatePlaceholder 模板中需要用户自行填写的值
+const templatePlaceholder = "<必填>"
+
+// configTemplate 内置预设或 templatesDir 中的用户模板，内容为带占位符的 TOML
+type configTemplate struct {
+	name string
+	text string // 内置模板的内容
+	path string // 用户模板的文件路径
+}
+
+// builtinTemplates 常见场景的预设
+var builtinTemplates = []configTemplate{
+	{name: "远程桌面 (RDP)", text: `serverAddr = "<必填>"
+serverPort = "<必填>"
+[auth]
+token = "<必填>"
+[[proxies]]
+name = "rdp"
+type = "tcp"
+localIP = "127.0.0.1"
+localPort = 3389
+remotePort = "<必填>"
+`},
+	{name: "SSH", text: `serverAddr = "<必填>"
+serverPort = "<必填>"
+[auth]
+token = "<必填>"
+[[proxies]]
+name = "ssh"
+type = "tcp"
+localIP = "127.0.0.1"
+localPort = 22
+remotePort = "<必填>"
+`},
+	{name: "SSH 安全访问 (stcp 访问端)", text: `serverAddr = "<必填>"
+serverPort = "<必填>"
+[auth]
+token = "<必填>"
+[[visitors]]
+name = "ssh-visitor"
+type = "stcp"
+serverName = "<必填>"
+secretKey = "<必填>"
+bindAddr = "127.0.0.1"
+bindPort = 6000
+`},
+	{name: "网站 (HTTP)", text: `serverAddr = "<必填>"
+serverPort = "<必填>"
+[auth]
+token = "<必填>"
+[[proxies]]
+name = "web"
+type = "http"
+localIP = "127.0.0.1"
+localPort = 80
+customDomains = ["<必填>"]
+`},
+	{name: "Minecraft 服务器", text: `serverAddr = "<必填>"
+serverPort = "<必填>"
+[auth]
+token = "<必填>"
+[[proxies]]
+name = "minecraft"
+type = "tcp"
+localIP = "127.0.0.1"
+localPort = 25565
+remotePort = "<必填>"
+`},
+}
+
+// listTemplates 返回内置预设和 dir 中的用户模板，dir 不存在时只返回内置预设
+func listTemplates(dir string) ([]configTemplate, error) {
+	templates := append([]configTemplate(nil), builtinTemplates...)
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return templates, nil
+	}
+	if err != nil {
+		return templates, fmt.Errorf("读取模板目录失败: %v", err)
+	}
+	for _, file := range files {
+		if !file.IsDir() && configFormat(file.Name()) == formatTOML {
+			templates = append(templates, configTemplate{
+				name: profileName(file.Name()),
+				path: filepath.Join(dir, file.Name()),
+			})
+		}
+	}
+	return templates, nil
+}
+
+// loadTemplate 解析模板，返回去掉占位符后的配置和需要用户填写的字段路径
+func loadTemplate(t configTemplate) (*ClientConfig, []string, error) {
+	text := t.text
+	if t.path != "" {
+		data, err := os.ReadFile(t.path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("读取模板失败: %v", err)
+		}
+		text = string(data)
+	}
+	var raw map[string]any
+	if _, err := toml.Decode(text, &raw); err != nil {
+		return nil, nil, fmt.Errorf("解析模板 %s 失败: %v", t.name, err)
+	}
+	fields := stripPlaceholders(raw, "")
+	cfg, err := configFromMap(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(fields)
+	return cfg, fields, nil
+}
+
+// stripPlaceholders 删除值为占位符的键，返回它们的字段路径，如 proxies[0].remotePort
+func stripPlaceholders(m map[string]any, prefix string) []string {
+	var fields []string
+	for key, val := range m {
+		field := key
+		if prefix != "" {
+			field = prefix + "." + key
+		}
+		switch val := val.(type) {
+		case string:
+			if val == templatePlaceholder {
+				delete(m, key)
+				fields = append(fields, field)
+			}
+		case []any:
+			for _, item := range val {
+				if item == templatePlaceholder {
+					delete(m, key)
+					fields = append(fields, field)
+					break
+				}
+			}
+		case map[string]any:
+			fields = append(fields, stripPlaceholders(val, field)...)
+		case []map[string]any:
+			for i, item := range val {
+				fields = append(fields, stripPlaceholders(item, fmt.Sprintf("%s[%d]", field, i))...)
+			}
+		}
+	}
+	return fields
+}
+
+// templateSecrets 保存为模板时替换为占位符的键，包括服务器地址和各类密钥
+var templateSecrets = struct {
+	common, visitor, proxy []toml.Key
+}{
+	common:  []toml.Key{{"serverAddr"}, {"auth", "token"}, {"auth", "oidc", "clientSecret"}},
+	visitor: []toml.Key{{"secretKey"}},
+	proxy:   []toml.Key{{"secretKey"}, {"httpPassword"}, {"loadBalancer", "groupKey"}, {"plugin", "httpPassword"}, {"plugin", "password"}},
+}
+
+// saveTemplate 把已有配置保存为用户模板，服务器地址和密钥替换为占位符
+func saveTemplate(path string, cfg *ClientConfig) error {
+	m, err := configToMap(cfg)
+	if err != nil {
+		return err
+	}
+	replace := func(m map[string]any, keys []toml.Key) {
+		for _, key := range keys {
+			if _, ok := lookupKey(m, key); ok {
+				setKey(m, key, templatePlaceholder)
+			}
+		}
+	}
+	replace(m, templateSecrets.common)
+	visitors, _ := m["visitors"].([]map[string]any)
+	for _, v := range visitors {
+		replace(v, templateSecrets.visitor)
+	}
+	proxies, _ := m["proxies"].([]map[string]any)
+	for _, p := range proxies {
+		replace(p, templateSecrets.proxy)
+	}
+
+	var buf bytes.Buffer
+	enc := toml.NewEncoder(&buf)
+	enc.Indent = ""
+	if err := enc.Encode(m); err != nil {
+		return fmt.Errorf("编码模板失败: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("创建模板目录失败: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("保存模板失败: %v", err)
+	}
+	return nil
+}
+
+// templateFileName 用户模板的文件名
+func templateFileName(name string) string {
+	return sanitizeProfileName(strings.TrimSuffix(name, ".toml")) + ".toml"
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// FieldError 指向配置中某个具体字段的校验错误
+type FieldError struct {
+	Field string // 字段路径，如 proxies[1].remotePort
+	Msg   string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Msg
+}
+
+// ValidationErrors 一次校验发现的全部错误
+type ValidationErrors []FieldError
+
+func (errs ValidationErrors) Error() string {
+	lines := make([]string, len(errs))
+	for i, e := range errs {
+		lines[i] = e.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+var (
+	proxyTypes   = []string{"tcp", "udp", "http", "https", "tcpmux", "xtcp", "stcp", "sudp"}
+	visitorTypes = []string{"xtcp", "stcp", "sudp"}
+	authMethods  = []string{"token", "oidc"}
+	authScopes   = []string{"HeartBeats", "NewWorkConns"}
+	protocols    = []string{"tcp", "kcp", "quic", "websocket", "wss"}
+	pluginTypes  = []string{"unix_domain_socket", "http_proxy", "socks5", "static_file", "https2http", "http2https"}
+
+	bandwidthLimitModes   = []string{"client", "server"}
+	bandwidthLimitPattern = regexp.MustCompile(`^[0-9]+(KB|MB)$`)
+)
+
+// validateConfig 检查整份配置，保存和启动 FRP 前都会调用
+func validateConfig(cfg *ClientConfig) ValidationErrors {
+	var errs ValidationErrors
+	add := func(field, format string, args ...any) {
+		errs = append(errs, FieldError{Field: field, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	// 与 frpc 一样先替换 {{ .Envs.NAME }}，再校验替换后的值
+	resolved, missing, err := cfg.resolveEnvs()
+	for _, name := range missing {
+		add("envs", "缺少环境变量 %s", name)
+	}
+	if err != nil {
+		add("envs", "%v", err)
+		return errs
+	}
+	cfg = resolved
+
+	if cfg.ServerAddr == "" {
+		add("serverAddr", "服务器地址不能为空")
+	} else if trimBrackets(cfg.ServerAddr) != cfg.ServerAddr {
+		add("serverAddr", "IPv6 地址不需要加方括号")
+	} else if !validHost(cfg.ServerAddr) {
+		add("serverAddr", "无效的服务器地址，应为 IP 或域名")
+	}
+	// 未设置时 frpc 使用默认端口 7000
+	if cfg.ServerPort < 0 || cfg.ServerPort > 65535 {
+		add("serverPort", "端口范围应为 1-65535")
+	}
+	validateAuth(cfg.Auth, add)
+
+	validateTransport(cfg.Transport, add)
+
+	visitorNames := map[string]int{}
+	bindPorts := map[string]int{}
+	for i, v := range cfg.Visitors {
+		field := fmt.Sprintf("visitors[%d]", i)
+		if v.Name == "" {
+			add(field+".name", "名称不能为空")
+		} else if j, ok := visitorNames[v.Name]; ok {
+			add(field+".name", "名称与 visitors[%d] 重复", j)
+		} else {
+			visitorNames[v.Name] = i
+		}
+		if !contains(visitorTypes, v.Type) {
+			add(field+".type", "请选择类型")
+		}
+		if v.ServerName == "" {
+			add(field+".serverName", "服务器名称不能为空")
+		}
+		if v.SecretKey == "" {
+			add(field+".secretKey", "密钥不能为空")
+		}
+		if v.BindAddr != "" && net.ParseIP(v.BindAddr) == nil {
+			add(field+".bindAddr", "无效的绑定地址")
+		}
+		// 与 frpc 一致，bindPort 小于 0 表示不监听本地端口
+		if v.BindPort == 0 || v.BindPort > 65535 {
+			add(field+".bindPort", "绑定端口不能为空且不能超过 65535")
+		} else if v.BindPort > 0 {
+			key := net.JoinHostPort(v.BindAddr, fmt.Sprint(v.BindPort))
+			if j, ok := bindPorts[key]; ok {
+				add(field+".bindPort", "绑定端口与 visitors[%d] 冲突", j)
+			} else {
+				bindPorts[key] = i
+			}
+		}
+	}
+
+	proxyNames := map[string]int{}
+	remotePorts := map[string]int{}
+	routes := map[string]int{}
+	for i, p := range cfg.Proxies {
+		field := fmt.Sprintf("proxies[%d]", i)
+		if p.Name == "" {
+			add(field+".name", "名称不能为空")
+		} else if j, ok := proxyNames[p.Name]; ok {
+			add(field+".name", "名称与 proxies[%d] 重复", j)
+		} else {
+			proxyNames[p.Name] = i
+		}
+		if !contains(proxyTypes, p.Type) {
+			add(field+".type", "请选择类型")
+		}
+		if p.Plugin != nil {
+			// 使用插件时由插件处理流量，不需要本地地址和端口
+			validatePlugin(p, field+".plugin", add)
+		} else {
+			if p.LocalIP != "" && !validHost(p.LocalIP) {
+				add(field+".localIP", "无效的本地地址")
+			}
+			if p.LocalPort <= 0 || p.LocalPort > 65535 {
+				add(field+".localPort", "端口范围应为 1-65535")
+			}
+		}
+
+		validateProxyTransport(p.Transport, field+".transport", add)
+		if p.LoadBalancer.Group != "" && p.Type != "tcp" && p.Type != "http" {
+			add(field+".loadBalancer.group", "只有 tcp 和 http 代理支持负载均衡分组")
+		}
+		if p.LoadBalancer.GroupKey != "" && p.LoadBalancer.Group == "" {
+			add(field+".loadBalancer.group", "填写分组密钥时必须填写分组名称")
+		}
+		if p.HealthCheck.Type != "" {
+			validateHealthCheck(p, field+".healthCheck", add)
+		}
+
+		switch p.Type {
+		case "tcp", "udp":
+			// remotePort 为 0 时由服务端随机分配，不参与冲突检查
+			if p.RemotePort < 0 || p.RemotePort > 65535 {
+				add(field+".remotePort", "端口范围应为 0-65535")
+			} else if p.RemotePort > 0 {
+				key := fmt.Sprintf("%s/%d", p.Type, p.RemotePort)
+				if j, ok := remotePorts[key]; ok && !sameGroup(p, cfg.Proxies[j]) {
+					add(field+".remotePort", "远程端口与 proxies[%d] 冲突", j)
+				} else {
+					remotePorts[key] = i
+				}
+			}
+		case "http", "https", "tcpmux":
+			if len(p.CustomDomains) == 0 && p.SubDomain == "" {
+				add(field+".customDomains", "%s 类型必须填写自定义域名或子域名", p.Type)
+			}
+			if strings.Contains(p.SubDomain, ".") {
+				add(field+".subdomain", "子域名不能包含 \".\"")
+			}
+			for _, loc := range p.Locations {
+				if !strings.HasPrefix(loc, "/") {
+					add(field+".locations", "路径 %q 必须以 / 开头", loc)
+				}
+			}
+			if p.Type == "http" {
+				for _, domain := range routeDomains(p) {
+					for _, loc := range routeLocations(p) {
+						key := domain + loc
+						if j, ok := routes[key]; ok && !sameGroup(p, cfg.Proxies[j]) {
+							add(field+".customDomains", "域名 %s 与 proxies[%d] 冲突", key, j)
+						} else {
+							routes[key] = i
+						}
+					}
+				}
+			}
+			if p.Type == "tcpmux" && p.Multiplexer != "httpconnect" {
+				add(field+".multiplexer", "tcpmux 目前只支持 httpconnect 复用器")
+			}
+		case "stcp", "xtcp", "sudp":
+			if p.SecretKey == "" {
+				add(field+".secretKey", "%s 类型必须填写密钥", p.Type)
+			}
+		}
+	}
+	return errs
+}
+
+// validateAuth 检查鉴权方式及其必填项
+func validateAuth(auth AuthConfig, add func(field, format string, args ...any)) {
+	switch auth.Method {
+	case "", "token":
+		if auth.Token == "" {
+			add("auth.token", "鉴权 Token 不能为空")
+		}
+	case "oidc":
+		if auth.OIDC.ClientID == "" {
+			add("auth.oidc.clientID", "Client ID 不能为空")
+		}
+		if auth.OIDC.ClientSecret == "" {
+			add("auth.oidc.clientSecret", "Client Secret 不能为空")
+		}
+		if auth.OIDC.TokenEndpointURL == "" {
+			add("auth.oidc.tokenEndpointURL", "Token 端点不能为空")
+		} else if u, err := url.Parse(auth.OIDC.TokenEndpointURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("auth.oidc.tokenEndpointURL", "Token 端点应为 http 或 https 地址")
+		}
+	default:
+		add("auth.method", "不支持的鉴权方式 %s", auth.Method)
+	}
+	for _, scope := range auth.AdditionalScopes {
+		if !contains(authScopes, scope) {
+			add("auth.additionalScopes", "不支持的鉴权范围 %s", scope)
+		}
+	}
+}
+
+// validateTransport 检查传输设置及其组合
+func validateTransport(t ClientTransport, add func(field, format string, args ...any)) {
+	if t.Protocol != "" && !contains(protocols, t.Protocol) {
+		add("transport.protocol", "不支持的传输协议 %s", t.Protocol)
+	}
+	if t.ConnectServerLocalIP != "" && net.ParseIP(t.ConnectServerLocalIP) == nil {
+		add("transport.connectServerLocalIP", "无效的本地地址")
+	}
+	if t.PoolCount < 0 {
+		add("transport.poolCount", "连接池数量不能为负数")
+	}
+
+	// heartbeatInterval 为 -1 表示关闭心跳
+	if t.HeartbeatInterval < -1 {
+		add("transport.heartbeatInterval", "心跳间隔应大于 0，或为 -1 表示关闭")
+	}
+	if t.HeartbeatTimeout < -1 {
+		add("transport.heartbeatTimeout", "心跳超时应大于 0，或为 -1 表示关闭")
+	}
+	if t.HeartbeatInterval > 0 && t.HeartbeatTimeout > 0 && t.HeartbeatTimeout <= t.HeartbeatInterval {
+		add("transport.heartbeatTimeout", "心跳超时必须大于心跳间隔")
+	}
+	if t.TCPMux != nil && !*t.TCPMux && t.HeartbeatInterval == -1 {
+		add("transport.heartbeatInterval", "关闭 tcpMux 时不能同时关闭心跳，否则无法发现断线")
+	}
+
+	tls := t.TLS
+	if (tls.CertFile == "") != (tls.KeyFile == "") {
+		add("transport.tls.certFile", "证书和私钥需要同时填写")
+	}
+	tlsDisabled := tls.Enable != nil && !*tls.Enable
+	if tlsDisabled && (tls.CertFile != "" || tls.TrustedCaFile != "" || tls.ServerName != "") {
+		add("transport.tls.enable", "未启用 TLS 时不能设置证书或服务器名称")
+	}
+	if tlsDisabled && t.Protocol == "wss" {
+		add("transport.tls.enable", "wss 协议需要启用 TLS")
+	}
+}
+
+// sameGroup 同一负载均衡分组的代理可以共用远程端口和路由
+func sameGroup(a, b Proxy) bool {
+	return a.LoadBalancer.Group != "" && a.LoadBalancer.Group == b.LoadBalancer.Group
+}
+
+// validateProxyTransport 检查代理的限速设置
+func validateProxyTransport(t ProxyTransport, field string, add func(field, format string, args ...any)) {
+	if t.BandwidthLimit != "" && !bandwidthLimitPattern.MatchString(t.BandwidthLimit) {
+		add(field+".bandwidthLimit", "带宽限制 %q 格式应为数字加 KB 或 MB，如 1MB", t.BandwidthLimit)
+	}
+	if t.BandwidthLimitMode != "" {
+		if !contains(bandwidthLimitModes, t.BandwidthLimitMode) {
+			add(field+".bandwidthLimitMode", "限速模式只能是 %s", strings.Join(bandwidthLimitModes, "/"))
+		} else if t.BandwidthLimit == "" {
+			add(field+".bandwidthLimit", "设置限速模式时必须填写带宽限制")
+		}
+	}
+}
+
+// validateHealthCheck 检查健康检查类型是否适用于该代理
+func validateHealthCheck(p Proxy, field string, add func(field, format string, args ...any)) {
+	hc := p.HealthCheck
+	switch hc.Type {
+	case "tcp":
+		if p.Type == "udp" || p.Type == "sudp" {
+			add(field+".type", "%s 代理不支持 tcp 健康检查", p.Type)
+		}
+	case "http":
+		// http 检查会向本地服务发送 HTTP 请求，只对承载 HTTP 流量的代理有意义
+		if !contains([]string{"tcp", "http", "https", "tcpmux"}, p.Type) {
+			add(field+".type", "%s 代理不支持 http 健康检查", p.Type)
+		}
+		if !strings.HasPrefix(hc.Path, "/") {
+			add(field+".path", "http 健康检查的路径必须以 / 开头")
+		}
+	default:
+		add(field+".type", "健康检查类型只能是 tcp 或 http")
+		return
+	}
+	if p.Plugin != nil {
+		add(field+".type", "使用插件的代理没有本地服务，无法进行健康检查")
+	}
+	if hc.TimeoutSeconds < 0 {
+		add(field+".timeoutSeconds", "超时时间不能为负数")
+	}
+	if hc.MaxFailed < 0 {
+		add(field+".maxFailed", "失败次数不能为负数")
+	}
+	if hc.IntervalSeconds < 0 {
+		add(field+".intervalSeconds", "检查间隔不能为负数")
+	}
+	if hc.TimeoutSeconds > 0 && hc.IntervalSeconds > 0 && hc.TimeoutSeconds >= hc.IntervalSeconds {
+		add(field+".timeoutSeconds", "超时时间应小于检查间隔")
+	}
+}
+
+// validatePlugin 检查插件类型及其必填参数
+func validatePlugin(p Proxy, field string, add func(field, format string, args ...any)) {
+	plugin := p.Plugin
+	if !contains(pluginTypes, plugin.Type) {
+		add(field+".type", "请选择插件类型")
+		return
+	}
+	switch plugin.Type {
+	case "unix_domain_socket":
+		if plugin.UnixPath == "" {
+			add(field+".unixPath", "Unix 套接字路径不能为空")
+		}
+	case "static_file":
+		if plugin.LocalPath == "" {
+			add(field+".localPath", "本地目录不能为空")
+		}
+	case "https2http", "http2https":
+		if plugin.LocalAddr == "" {
+			add(field+".localAddr", "本地服务地址不能为空")
+		} else if _, _, err := net.SplitHostPort(plugin.LocalAddr); err != nil {
+			add(field+".localAddr", "本地服务地址应为 host:port")
+		}
+		if (plugin.CrtPath == "") != (plugin.KeyPath == "") {
+			add(field+".crtPath", "证书和私钥需要同时填写")
+		}
+	}
+
+	// 协议转换插件只能挂在对应协议的代理上
+	if plugin.Type == "https2http" && p.Type != "https" {
+		add(field+".type", "https2http 插件只能用于 https 类型的代理")
+	}
+	if plugin.Type == "http2https" && p.Type != "http" {
+		add(field+".type", "http2https 插件只能用于 http 类型的代理")
+	}
+}
+
+// routeDomains 返回 http 代理实际占用的域名，子域名以 * 代替服务端的根域名
+func routeDomains(p Proxy) []string {
+	domains := append([]string(nil), p.CustomDomains...)
+	if p.SubDomain != "" {
+		domains = append(domains, p.SubDomain+".*")
+	}
+	return domains
+}
+
+func routeLocations(p Proxy) []string {
+	if len(p.Locations) == 0 {
+		return []string{"/"}
+	}
+	return p.Locations
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
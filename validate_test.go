@@ -0,0 +1,142 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// validConfig 能通过校验的最小配置，各用例在此基础上修改
+func validConfig() *ClientConfig {
+	return &ClientConfig{
+		ServerAddr: "frp.example.com",
+		ServerPort: 7000,
+		Auth:       AuthConfig{Token: "secret"},
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *ClientConfig)
+		want   []string // 期望报错的字段
+	}{
+		{"有效配置", func(cfg *ClientConfig) {}, nil},
+		{"未设置 serverPort 时使用默认端口", func(cfg *ClientConfig) { cfg.ServerPort = 0 }, nil},
+		{"serverPort 超出范围", func(cfg *ClientConfig) { cfg.ServerPort = 70000 }, []string{"serverPort"}},
+		{"serverPort 为负数", func(cfg *ClientConfig) { cfg.ServerPort = -1 }, []string{"serverPort"}},
+		{"token 为空", func(cfg *ClientConfig) { cfg.Auth.Token = "" }, []string{"auth.token"}},
+		{"method 为 token 时 token 为空", func(cfg *ClientConfig) { cfg.Auth = AuthConfig{Method: "token"} }, []string{"auth.token"}},
+		{"oidc 不需要 token", func(cfg *ClientConfig) {
+			cfg.Auth = AuthConfig{Method: "oidc", OIDC: OIDCConfig{ClientID: "id", ClientSecret: "s", TokenEndpointURL: "https://auth.example.com/token"}}
+		}, nil},
+		{"代理名称重复", func(cfg *ClientConfig) {
+			cfg.Proxies = []Proxy{
+				{Name: "ssh", Type: "tcp", LocalPort: 22, RemotePort: 6000},
+				{Name: "ssh", Type: "tcp", LocalPort: 23, RemotePort: 6001},
+			}
+		}, []string{"proxies[1].name"}},
+		{"visitor 名称重复", func(cfg *ClientConfig) {
+			cfg.Visitors = []Visitor{
+				{Name: "v", Type: "stcp", ServerName: "a", SecretKey: "k", BindPort: 6000},
+				{Name: "v", Type: "stcp", ServerName: "b", SecretKey: "k", BindPort: 6001},
+			}
+		}, []string{"visitors[1].name"}},
+		{"bindPort 冲突", func(cfg *ClientConfig) {
+			cfg.Visitors = []Visitor{
+				{Name: "a", Type: "stcp", ServerName: "a", SecretKey: "k", BindPort: 6000},
+				{Name: "b", Type: "xtcp", ServerName: "b", SecretKey: "k", BindPort: 6000},
+			}
+		}, []string{"visitors[1].bindPort"}},
+		{"不同地址的 bindPort 不冲突", func(cfg *ClientConfig) {
+			cfg.Visitors = []Visitor{
+				{Name: "a", Type: "stcp", ServerName: "a", SecretKey: "k", BindAddr: "127.0.0.1", BindPort: 6000},
+				{Name: "b", Type: "stcp", ServerName: "b", SecretKey: "k", BindAddr: "127.0.0.2", BindPort: 6000},
+			}
+		}, nil},
+		{"bindPort 为 -1 时不监听", func(cfg *ClientConfig) {
+			cfg.Visitors = []Visitor{
+				{Name: "a", Type: "xtcp", ServerName: "a", SecretKey: "k", BindPort: -1},
+				{Name: "b", Type: "xtcp", ServerName: "b", SecretKey: "k", BindPort: -1},
+			}
+		}, nil},
+		{"visitor 缺少 secretKey", func(cfg *ClientConfig) {
+			cfg.Visitors = []Visitor{{Name: "v", Type: "stcp", ServerName: "a", BindPort: 6000}}
+		}, []string{"visitors[0].secretKey"}},
+		{"remotePort 冲突", func(cfg *ClientConfig) {
+			cfg.Proxies = []Proxy{
+				{Name: "a", Type: "tcp", LocalPort: 22, RemotePort: 6000},
+				{Name: "b", Type: "tcp", LocalPort: 23, RemotePort: 6000},
+			}
+		}, []string{"proxies[1].remotePort"}},
+		{"tcp 和 udp 的 remotePort 不冲突", func(cfg *ClientConfig) {
+			cfg.Proxies = []Proxy{
+				{Name: "a", Type: "tcp", LocalPort: 53, RemotePort: 6000},
+				{Name: "b", Type: "udp", LocalPort: 53, RemotePort: 6000},
+			}
+		}, nil},
+		{"同一分组可以共用 remotePort", func(cfg *ClientConfig) {
+			cfg.Proxies = []Proxy{
+				{Name: "a", Type: "tcp", LocalPort: 80, RemotePort: 6000, LoadBalancer: LoadBalancerConfig{Group: "web"}},
+				{Name: "b", Type: "tcp", LocalPort: 81, RemotePort: 6000, LoadBalancer: LoadBalancerConfig{Group: "web"}},
+			}
+		}, nil},
+		{"remotePort 为 0 时随机分配", func(cfg *ClientConfig) {
+			cfg.Proxies = []Proxy{
+				{Name: "a", Type: "tcp", LocalPort: 22},
+				{Name: "b", Type: "tcp", LocalPort: 23},
+			}
+		}, nil},
+		{"stcp 缺少 secretKey", func(cfg *ClientConfig) {
+			cfg.Proxies = []Proxy{{Name: "a", Type: "stcp", LocalPort: 22}}
+		}, []string{"proxies[0].secretKey"}},
+		{"xtcp 缺少 secretKey", func(cfg *ClientConfig) {
+			cfg.Proxies = []Proxy{{Name: "a", Type: "xtcp", LocalPort: 22}}
+		}, []string{"proxies[0].secretKey"}},
+		{"sudp 缺少 secretKey", func(cfg *ClientConfig) {
+			cfg.Proxies = []Proxy{{Name: "a", Type: "sudp", LocalPort: 53}}
+		}, []string{"proxies[0].secretKey"}},
+		{"udp 不支持 tcp 健康检查", func(cfg *ClientConfig) {
+			cfg.Proxies = []Proxy{{Name: "a", Type: "udp", LocalPort: 53, HealthCheck: HealthCheckConfig{Type: "tcp"}}}
+		}, []string{"proxies[0].healthCheck.type"}},
+		{"sudp 不支持 http 健康检查", func(cfg *ClientConfig) {
+			cfg.Proxies = []Proxy{{Name: "a", Type: "sudp", SecretKey: "k", LocalPort: 53, HealthCheck: HealthCheckConfig{Type: "http", Path: "/"}}}
+		}, []string{"proxies[0].healthCheck.type"}},
+		{"tcp 支持 http 健康检查", func(cfg *ClientConfig) {
+			cfg.Proxies = []Proxy{{Name: "a", Type: "tcp", LocalPort: 80, HealthCheck: HealthCheckConfig{Type: "http", Path: "/status"}}}
+		}, nil},
+		{"http 健康检查路径必须以 / 开头", func(cfg *ClientConfig) {
+			cfg.Proxies = []Proxy{{Name: "a", Type: "tcp", LocalPort: 80, HealthCheck: HealthCheckConfig{Type: "http", Path: "status"}}}
+		}, []string{"proxies[0].healthCheck.path"}},
+		{"插件代理不能健康检查", func(cfg *ClientConfig) {
+			cfg.Proxies = []Proxy{{Name: "a", Type: "tcp", Plugin: &ClientPlugin{Type: "socks5"}, HealthCheck: HealthCheckConfig{Type: "tcp"}}}
+		}, []string{"proxies[0].healthCheck.type"}},
+		{"未知的健康检查类型", func(cfg *ClientConfig) {
+			cfg.Proxies = []Proxy{{Name: "a", Type: "tcp", LocalPort: 22, HealthCheck: HealthCheckConfig{Type: "icmp"}}}
+		}, []string{"proxies[0].healthCheck.type"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(cfg)
+			var got []string
+			for _, e := range validateConfig(cfg) {
+				got = append(got, e.Field)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("报错字段 %v，期望 %v\n%v", got, tt.want, validateConfig(cfg))
+			}
+		})
+	}
+}
+
+func TestServerPortDefault(t *testing.T) {
+	cfg := validConfig()
+	cfg.ServerPort = 0
+	if port := cfg.serverPort(); port != defaultServerPort {
+		t.Errorf("未设置 serverPort 时应为 %d，得到 %d", defaultServerPort, port)
+	}
+	cfg.ServerPort = 7001
+	if port := cfg.serverPort(); port != 7001 {
+		t.Errorf("serverPort 应为 7001，得到 %d", port)
+	}
+}